@@ -0,0 +1,19 @@
+//go:build !unix
+
+package bubblecomplete
+
+import "os"
+
+// MARK: Private Functions
+
+// canWrite returns true if the file or directory at the OS path has a write permission bit set
+//
+// Without access checks, this relies on the permission bits, which on Windows reflect the read-only
+// attribute. Nothing is written to check it.
+func canWrite(name string) bool {
+	info, err := os.Stat(name)
+	if err != nil {
+		return false
+	}
+	return info.Mode().Perm()&0o222 != 0
+}
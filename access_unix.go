@@ -0,0 +1,12 @@
+//go:build unix
+
+package bubblecomplete
+
+import "golang.org/x/sys/unix"
+
+// MARK: Private Functions
+
+// canWrite returns true if the current user can write to the file or directory at the OS path
+func canWrite(name string) bool {
+	return unix.Access(name, unix.W_OK) == nil
+}
@@ -0,0 +1,223 @@
+package bubblecomplete
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MARK: Public Functions
+
+// Bind fills the fields of the struct pointed to by dst with the values of the entered command
+//
+// Fields are bound using `bc` struct tags, either `bc:"flag=--message"` for a flag (by its short
+// or long flag) or `bc:"arg=file"` for a positional argument (by its name). Values are converted
+// according to the argument type of the flag or positional argument, and fields for values that
+// weren't entered are left unchanged. Slice fields take every value of a repeatable flag or variadic
+// positional argument, and int fields the number of times a count flag was entered.
+//
+// Returns the message error if the command is invalid.
+func Bind(msg SelectedCommandMsg, dst any) error {
+	if msg.Err != nil {
+		return msg.Err
+	}
+	if msg.Parsed == nil {
+		return errors.New("no parsed command to bind")
+	}
+
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("bind destination must be a non-nil pointer to a struct")
+	}
+	v = v.Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		tag, ok := field.Tag.Lookup("bc")
+		if !ok {
+			continue
+		}
+
+		kind, name, found := strings.Cut(tag, "=")
+		if !found || name == "" || (kind != "flag" && kind != "arg") {
+			return fmt.Errorf("invalid bc tag on field %s: %q", field.Name, tag)
+		}
+		if !field.IsExported() {
+			return fmt.Errorf("cannot bind unexported field %s", field.Name)
+		}
+
+		var arg Argument
+		if kind == "flag" {
+			arg = msg.Parsed.flagDefinition(name)
+		} else {
+			arg = msg.Parsed.positionalDefinition(name)
+		}
+		if arg == nil {
+			return fmt.Errorf("%s '%s' not found for field %s", kindName(kind), name, field.Name)
+		}
+
+		if !msg.Parsed.Has(name) {
+			continue
+		}
+		if err := bindValue(v.Field(i), field.Name, arg, msg.Parsed, name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MARK: Private Functions
+
+func kindName(kind string) string {
+	if kind == "arg" {
+		return "positional argument"
+	}
+	return kind
+}
+
+func bindValue(field reflect.Value, fieldName string, arg Argument, parsed *ParsedCommand, name string) error {
+	// Slice fields take every value, except for JSON which is unmarshalled as a whole
+	if field.Kind() == reflect.Slice && arg.getType() != JSONArgument {
+		return bindValues(field, fieldName, arg, parsed, name)
+	}
+
+	value := parsed.String(name)
+
+	switch arg.getType() {
+	case FileArgument, DirArgument, FileDirArgument:
+		value = parsed.Path(name)
+	}
+
+	// String fields take the value of any argument type as is
+	if field.Kind() == reflect.String {
+		field.SetString(value)
+		return nil
+	}
+
+	switch arg.getType() {
+	case BoolArgument:
+		if field.Kind() == reflect.Bool {
+			b, err := parsed.Bool(name)
+			if err != nil {
+				return err
+			}
+			field.SetBool(b)
+			return nil
+		}
+		// Int fields take the number of times a count flag was entered
+		if flag, ok := arg.(*Flag); ok && flag.Count && field.CanInt() {
+			field.SetInt(int64(parsed.Count(name)))
+			return nil
+		}
+	case IntArgument:
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			i, err := strconv.ParseInt(value, 10, field.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("integer value for argument %s doesn't fit in field %s", name, fieldName)
+			}
+			field.SetInt(i)
+			return nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			u, err := strconv.ParseUint(value, 10, field.Type().Bits())
+			if err != nil {
+				return fmt.Errorf("integer value for argument %s doesn't fit in field %s", name, fieldName)
+			}
+			field.SetUint(u)
+			return nil
+		case reflect.Float32, reflect.Float64:
+			f, err := parsed.Float(name)
+			if err != nil {
+				return err
+			}
+			field.SetFloat(f)
+			return nil
+		}
+	case FloatArgument:
+		if field.Kind() == reflect.Float32 || field.Kind() == reflect.Float64 {
+			f, err := parsed.Float(name)
+			if err != nil {
+				return err
+			}
+			field.SetFloat(f)
+			return nil
+		}
+	case ByteSizeArgument:
+		switch field.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+			size, err := parsed.ByteSize(name)
+			if err != nil {
+				return err
+			}
+			if field.CanInt() {
+				field.SetInt(size)
+			} else {
+				field.SetUint(uint64(size))
+			}
+			return nil
+		}
+	case JSONArgument:
+		// The value is unmarshalled into the field, whatever its type
+		return parsed.JSON(name, field.Addr().Interface())
+	case DurationArgument, URLArgument, IPArgument, CIDRArgument, TimeArgument, RegexArgument:
+		typed, err := typedValue(parsed, name, arg.getType())
+		if err != nil {
+			return err
+		}
+		if typed.Type().AssignableTo(field.Type()) {
+			field.Set(typed)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("cannot bind %s argument %s to field %s of type %s", arg.getType(), name, fieldName, field.Type())
+}
+
+// bindValues fills the slice field with every value of the flag or positional argument, each
+// converted as a single value would be
+func bindValues(field reflect.Value, fieldName string, arg Argument, parsed *ParsedCommand, name string) error {
+	values := parsed.Strings(name)
+
+	slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+	for i, value := range values {
+		single := &ParsedCommand{
+			Commands:   parsed.Commands,
+			Flags:      map[string]string{name: value},
+			FlagValues: map[string][]string{name: {value}},
+			WorkingDir: parsed.WorkingDir,
+			fsys:       parsed.fsys,
+		}
+		if err := bindValue(slice.Index(i), fieldName, arg, single, name); err != nil {
+			return err
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// typedValue returns the value parsed with the typed accessor for the argument type
+func typedValue(parsed *ParsedCommand, name string, argType ArgumentType) (reflect.Value, error) {
+	var value any
+	var err error
+	switch argType {
+	case DurationArgument:
+		value, err = parsed.Duration(name)
+	case URLArgument:
+		value, err = parsed.URL(name)
+	case IPArgument:
+		value, err = parsed.IP(name)
+	case CIDRArgument:
+		value, err = parsed.CIDR(name)
+	case TimeArgument:
+		value, err = parsed.Time(name)
+	case RegexArgument:
+		value, err = parsed.Regex(name)
+	}
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return reflect.ValueOf(value), nil
+}
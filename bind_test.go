@@ -0,0 +1,162 @@
+package bubblecomplete
+
+import (
+	"errors"
+	"net/netip"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBind(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "serve",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "dir", Type: DirArgument},
+				{Name: "port", Type: IntArgument},
+			},
+			Flags: []*Flag{
+				{ShortFlag: "-n", LongFlag: "--name", Type: StringArgument},
+				{LongFlag: "--ratio", Type: FloatArgument},
+				{ShortFlag: "-v", Type: BoolArgument},
+				{LongFlag: "--open", Type: BoolArgument},
+			},
+		},
+	}
+
+	type options struct {
+		Dir     string  `bc:"arg=dir"`
+		Port    uint16  `bc:"arg=port"`
+		Name    string  `bc:"flag=--name"`
+		Ratio   float32 `bc:"flag=--ratio"`
+		Verbose bool    `bc:"flag=-v"`
+		Open    bool    `bc:"flag=--open"`
+		Other   string
+	}
+
+	p := parseInput("serve ./public/ 8080 -n 'my site' --ratio=0.5 -v", commands)
+	msg := SelectedCommandMsg{Parsed: newParsedCommand(p)}
+
+	opts := options{Open: true, Other: "unchanged"}
+	if err := Bind(msg, &opts); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := options{Dir: "public", Port: 8080, Name: "my site", Ratio: 0.5, Verbose: true, Open: true, Other: "unchanged"}
+	if opts != expected {
+		t.Errorf("Bind() == %+v, expected %+v", opts, expected)
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "serve",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "port", Type: IntArgument},
+			},
+		},
+	}
+	msg := SelectedCommandMsg{Parsed: newParsedCommand(parseInput("serve 70000", commands))}
+
+	var port struct {
+		Port uint16 `bc:"arg=port"`
+	}
+	if err := Bind(msg, &port); err == nil {
+		t.Errorf("expected an error binding an out of range value")
+	}
+
+	var flag struct {
+		Flag bool `bc:"flag=--missing"`
+	}
+	if err := Bind(msg, &flag); err == nil {
+		t.Errorf("expected an error binding an undefined flag")
+	}
+
+	var tag struct {
+		Port int `bc:"port"`
+	}
+	if err := Bind(msg, &tag); err == nil {
+		t.Errorf("expected an error binding an invalid tag")
+	}
+
+	var wrongType struct {
+		Port bool `bc:"arg=port"`
+	}
+	if err := Bind(msg, &wrongType); err == nil {
+		t.Errorf("expected an error binding to a field of the wrong type")
+	}
+
+	if err := Bind(msg, port); err == nil {
+		t.Errorf("expected an error binding to a non-pointer")
+	}
+
+	invalid := errors.New("invalid command")
+	if err := Bind(SelectedCommandMsg{Err: invalid}, &port); err != invalid {
+		t.Errorf("Bind() == %v, expected the message error", err)
+	}
+}
+
+func TestBindRichValues(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "deploy",
+			Flags: []*Flag{
+				{LongFlag: "--timeout", Type: DurationArgument},
+				{LongFlag: "--ip", Type: IPArgument},
+				{LongFlag: "--size", Type: ByteSizeArgument},
+				{LongFlag: "--labels", Type: JSONArgument},
+			},
+		},
+	}
+
+	var opts struct {
+		Timeout time.Duration     `bc:"flag=--timeout"`
+		IP      netip.Addr        `bc:"flag=--ip"`
+		Size    uint64            `bc:"flag=--size"`
+		Labels  map[string]string `bc:"flag=--labels"`
+	}
+
+	p := parseInput("deploy --timeout 5m --ip 10.0.0.1 --size 2KiB --labels '{\"env\": \"prod\"}'", commands)
+	if err := Bind(SelectedCommandMsg{Parsed: newParsedCommand(p)}, &opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.Timeout != 5*time.Minute || opts.IP != netip.MustParseAddr("10.0.0.1") || opts.Size != 2048 || opts.Labels["env"] != "prod" {
+		t.Errorf("Bind() == %+v", opts)
+	}
+}
+
+func TestBindRepeatedFlags(t *testing.T) {
+	var opts struct {
+		Verbose int      `bc:"flag=-v"`
+		Tags    []string `bc:"flag=--tag"`
+		Ports   []int    `bc:"flag=--port"`
+	}
+
+	p := parseInput("build -vv --tag a --tag b --port 80 --port 443", testRepeatableFlagCommands())
+	if err := Bind(SelectedCommandMsg{Parsed: newParsedCommand(p)}, &opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.Verbose != 2 || !slices.Equal(opts.Tags, []string{"a", "b"}) || !slices.Equal(opts.Ports, []int{80, 443}) {
+		t.Errorf("Bind() == %+v", opts)
+	}
+}
+
+func TestBindVariadicValues(t *testing.T) {
+	var opts struct {
+		Separator string `bc:"arg=separator"`
+		Parts     []int  `bc:"arg=part"`
+	}
+
+	p := parseInput("join + 4 5", testVariadicCommands())
+	if err := Bind(SelectedCommandMsg{Parsed: newParsedCommand(p)}, &opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.Separator != "+" || !slices.Equal(opts.Parts, []int{4, 5}) {
+		t.Errorf("Bind() == %+v", opts)
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -14,10 +16,35 @@ import (
 // MARK: Types and Vars
 
 type SelectedCommandMsg struct {
+	// The command as it was entered
 	Command string
-	Err     error
+	// The parsed command, with the resolved commands, flags and positional arguments
+	Parsed *ParsedCommand
+	// The validation error for the command, if it's invalid
+	Err error
+	// Warnings about the command that don't stop it being valid, such as for deprecated commands and flags
+	Warnings []string
 }
 
+// Context is passed to the Run handler of an entered command
+type Context struct {
+	// The command as it was entered
+	Input string
+	// The parsed command, with the resolved commands, flags and positional arguments
+	*ParsedCommand
+	// Warnings about the command that don't stop it being valid, such as for deprecated commands and flags
+	Warnings []string
+}
+
+// Handler handles a valid entered command, returning an error to reject it
+type Handler func(ctx Context) (tea.Cmd, error)
+
+// Middleware wraps the handler of entered commands, such as for logging or authorisation
+//
+// Middleware can call next to carry on handling the command, or return an error instead
+// to reject it, which is sent as the error of a SelectedCommandMsg.
+type Middleware func(next Handler) Handler
+
 type historyFileJson struct {
 	History []string `json:"history"`
 }
@@ -28,9 +55,29 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
 
-	// Handle key presses
+	// While the file picker is open, it takes all input until a path is picked or it's closed
+	if m.pickingPath {
+		return m.updateFilePicker(msg)
+	}
+
 	switch msg := msg.(type) {
+	case completionsMsg:
+		m.receiveCompletions(msg)
+		return m, nil
+	case spinner.TickMsg:
+		if !m.completionsPending {
+			return m, nil
+		}
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	// Handle key presses
 	case tea.KeyMsg:
+		if key.Matches(msg, m.FilePickerKey) {
+			if cmd, ok := m.openFilePicker(); ok {
+				return m, cmd
+			}
+		}
+
 		switch msg.String() {
 		case "tab", "ctrl+n", "shift+tab", "ctrl+p":
 			m, cmd = m.keyTab(msg.String())
@@ -54,11 +101,9 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 	m.input, cmd = m.input.Update(msg)
 	cmds = append(cmds, cmd)
 
-	// If the input has changed, update the completions and validate the input
+	// If the input has changed, parse it then update the completions and validate the input
 	if m.input.Value() != "" && m.input.Value() != m.lastInput && m.completionHolder == "" && !m.showAll {
-		m.lastInput = m.input.Value()
-		m.completions = m.getCompletions()
-		m.validCommand = m.validateInput()
+		cmds = append(cmds, m.inputChanged())
 	}
 
 	// If not loaded, start the blinking cursor
@@ -114,6 +159,13 @@ func (m *Model) SetHistoryFilePath(path string) {
 	}
 }
 
+// Use adds middleware that wraps the handling of valid entered commands
+//
+// Middleware is called in the order it's added, so the first middleware added is the outermost.
+func (m *Model) Use(middleware ...Middleware) {
+	m.middleware = append(m.middleware, middleware...)
+}
+
 // ClearHistory clears the command history from all previous commands.
 //
 // If the history file path is set, it also clears the history on file.
@@ -185,7 +237,43 @@ func (m *Model) loadHistoryFromFile() error {
 	return nil
 }
 
+// dispatchCommand runs the handler of the entered command, or sends a SelectedCommandMsg if it doesn't have one
+func dispatchCommand(ctx Context) (tea.Cmd, error) {
+	if cmd := ctx.Command(); cmd != nil && cmd.Run != nil {
+		return cmd.Run(ctx), nil
+	}
+	return selectedCommand(ctx.Input, ctx.ParsedCommand, ctx.Warnings, nil), nil
+}
+
+func selectedCommand(command string, parsed *ParsedCommand, warnings []string, err error) tea.Cmd {
+	return func() tea.Msg {
+		return SelectedCommandMsg{Command: command, Parsed: parsed, Err: err, Warnings: warnings}
+	}
+}
+
+// inputChanged parses the new input, then updates the completions and validates it
+func (m *Model) inputChanged() tea.Cmd {
+	m.lastInput = m.input.Value()
+	m.inputID++
+	m.parsed = m.parseInput(m.input.Value())
+	m.completionCache.configure(m.CompletionCacheTTL, m.CompletionCacheSize)
+	m.completions = m.getCompletions()
+	m.validCommand = m.validateInput()
+	m.pathHighlights = getPathHighlights(m.parsed)
+	return m.requestCompletions()
+}
+
+// parseInput parses the input against the commands, with the completion cache and filesystem of the model
+func (m Model) parseInput(input string) *parsedInput {
+	p := parseInput(input, m.Commands)
+	p.cache = m.completionCache
+	p.fsys = m.FS
+	p.workingDir = m.WorkingDir
+	return p
+}
+
 func (m Model) resetModel() Model {
+	m.cancelPendingCompletions()
 	m.input.SetValue("")
 	m.completions = []Completion{}
 	m.completionIndex = -1
@@ -193,44 +281,6 @@ func (m Model) resetModel() Model {
 	return m
 }
 
-func splitInput(input string) []string {
-	var result []string
-	var buffer strings.Builder
-	inQuotes := false
-	var quoteChar rune
-
-	flushBuffer := func() {
-		if buffer.Len() > 0 {
-			result = append(result, buffer.String())
-			buffer.Reset()
-		}
-	}
-
-	for _, char := range input {
-		switch {
-		case char == ' ' && !inQuotes:
-			flushBuffer()
-		case char == '"' || char == '\'':
-			if inQuotes && char == quoteChar {
-				inQuotes = false
-				buffer.WriteRune(char)
-				flushBuffer()
-			} else if !inQuotes {
-				inQuotes = true
-				quoteChar = char
-				buffer.WriteRune(char)
-			} else {
-				buffer.WriteRune(char)
-			}
-		default:
-			buffer.WriteRune(char)
-		}
-	}
-
-	flushBuffer()
-	return result
-}
-
 // MARK: Key Handlers
 
 func (m Model) keyUp() (Model, tea.Cmd) {
@@ -324,6 +374,9 @@ func (m Model) keyTab(input string) (Model, tea.Cmd) {
 		}
 	}
 
+	// Cycling changes the input without starting a new request, so stop waiting for the current one
+	m.cancelPendingCompletions()
+
 	// Cycle and update the completion index
 	if input == "tab" || input == "ctrl+n" {
 		// Down
@@ -361,15 +414,8 @@ func (m Model) keyTab(input string) (Model, tea.Cmd) {
 		return m, nil
 	}
 
-	pretext := m.completionHolder
-	parts := splitInput(m.completionHolder)
-	// If the pretext doesn't end in a space, add one
-	if !strings.HasSuffix(pretext, " ") && len(parts) > 0 {
-		pretext = strings.Join(parts[:len(parts)-1], " ")
-		if len(parts) > 1 {
-			pretext += " "
-		}
-	}
+	// If we're part way through typing a token or flag value, replace it with the completion
+	pretext := m.completionHolder[:parseInput(m.completionHolder, m.Commands).replaceStart()]
 
 	// Update the input with the current completion
 	m.input.SetValue(pretext + m.completions[m.completionIndex].getAutocomplete())
@@ -394,12 +440,35 @@ func (m Model) keyEnter() (Model, tea.Cmd) {
 	m.saveHistoryToFile()
 	m.input.SetSuggestions(m.History)
 
-	return m, func() tea.Msg {
-		return SelectedCommandMsg{Command: command, Err: m.validCommand}
+	// Parse the command again as the input may have changed through completions since it was last parsed
+	parsed := m.parseInput(command)
+	var err error
+	if command != "" {
+		err = validateCommandInput(parsed)
+	}
+
+	result := newParsedCommand(parsed)
+	warnings := deprecationWarnings(parsed)
+	if err != nil || command == "" {
+		return m, selectedCommand(command, result, warnings, err)
+	}
+
+	// Wrap the handler with the middleware, with the first middleware added as the outermost
+	handler := dispatchCommand
+	for i := len(m.middleware) - 1; i >= 0; i-- {
+		handler = m.middleware[i](handler)
+	}
+
+	cmd, err := handler(Context{Input: command, ParsedCommand: result, Warnings: warnings})
+	if err != nil {
+		return m, selectedCommand(command, result, warnings, err)
 	}
+	return m, cmd
 }
 
 func (m Model) keyBackspace() (Model, tea.Cmd) {
+	// A new request is only started if the input isn't left empty
+	m.cancelPendingCompletions()
 	m.completionHolder = ""
 	m.completionIndex = -1
 	m.historyIndex = -1
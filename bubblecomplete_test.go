@@ -0,0 +1,156 @@
+package bubblecomplete
+
+import (
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type ranMsg struct {
+	message string
+}
+
+func enterCommand(t *testing.T, m Model, input string) tea.Msg {
+	t.Helper()
+	m.input.SetValue(input)
+	_, cmd := m.keyEnter()
+	if cmd == nil {
+		return nil
+	}
+	return cmd()
+}
+
+func TestKeyEnterRun(t *testing.T) {
+	commands := testCommands()
+	commit := commands[0].SubCommands[2]
+	commit.Run = func(ctx Context) tea.Cmd {
+		return func() tea.Msg {
+			return ranMsg{message: ctx.String("--message")}
+		}
+	}
+
+	m, err := New(commands, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := enterCommand(t, m, "git commit -m hello")
+	if ran, ok := msg.(ranMsg); !ok || ran.message != "hello" {
+		t.Errorf("expected the commit handler to run, got %#v", msg)
+	}
+
+	// Invalid commands aren't dispatched
+	msg = enterCommand(t, m, "git commit -m")
+	if selected, ok := msg.(SelectedCommandMsg); !ok || selected.Err == nil {
+		t.Errorf("expected a SelectedCommandMsg with an error, got %#v", msg)
+	}
+
+	// Commands without handlers still send the message
+	msg = enterCommand(t, m, "git status")
+	if selected, ok := msg.(SelectedCommandMsg); !ok || selected.Err != nil || selected.Parsed.Command().Command != "status" {
+		t.Errorf("expected a SelectedCommandMsg for status, got %#v", msg)
+	}
+}
+
+func TestUseMiddleware(t *testing.T) {
+	m, err := New(testCommands(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	m.Use(
+		func(next Handler) Handler {
+			return func(ctx Context) (tea.Cmd, error) {
+				calls = append(calls, "outer")
+				return next(ctx)
+			}
+		},
+		func(next Handler) Handler {
+			return func(ctx Context) (tea.Cmd, error) {
+				calls = append(calls, "inner")
+				if ctx.Command().Command == "commit" {
+					return nil, errors.New("not allowed")
+				}
+				return next(ctx)
+			}
+		},
+	)
+
+	msg := enterCommand(t, m, "git status")
+	if selected, ok := msg.(SelectedCommandMsg); !ok || selected.Err != nil {
+		t.Errorf("expected a SelectedCommandMsg without an error, got %#v", msg)
+	}
+	if len(calls) != 2 || calls[0] != "outer" || calls[1] != "inner" {
+		t.Errorf("middleware called as %q, expected outer then inner", calls)
+	}
+
+	msg = enterCommand(t, m, "git commit")
+	if selected, ok := msg.(SelectedCommandMsg); !ok || selected.Err == nil || selected.Err.Error() != "not allowed" {
+		t.Errorf("expected a SelectedCommandMsg with the middleware error, got %#v", msg)
+	}
+
+	// Invalid commands skip the middleware
+	calls = nil
+	enterCommand(t, m, "git nope")
+	if len(calls) != 0 {
+		t.Errorf("expected no middleware calls for an invalid command, got %q", calls)
+	}
+}
+
+func TestKeyTabValues(t *testing.T) {
+	commands := testCommands()
+	commit := commands[0].SubCommands[2]
+	commit.Flags[0].Completer = func(partial string, parsed *ParsedCommand) []Suggestion {
+		return []Suggestion{{Value: "initial commit"}}
+	}
+
+	m, err := New(commands, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"git commit -m ", "git commit -m \"initial commit\""},
+		{"git commit -m ini", "git commit -m \"initial commit\""},
+		{"git commit --message=", "git commit --message=\"initial commit\""},
+		{"git commit  --am", "git commit  --amend"},
+	}
+
+	for _, c := range cases {
+		m.input.SetValue(c.input)
+		m.lastInput = ""
+		m, _ = m.Update(nil)
+		m, _ = m.keyTab("tab")
+		if m.input.Value() != c.expected {
+			t.Errorf("keyTab() with input %q == %q, expected %q", c.input, m.input.Value(), c.expected)
+		}
+		m = m.resetModel()
+		m.completionHolder = ""
+	}
+}
+
+func TestKeyEnterWarnings(t *testing.T) {
+	commands := testCommands()
+	commands[0].SubCommands[2].Deprecated = "use save instead"
+
+	m, err := New(commands, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := enterCommand(t, m, "git commit -m hello")
+	selected, ok := msg.(SelectedCommandMsg)
+	if !ok || selected.Err != nil || len(selected.Warnings) != 1 || selected.Warnings[0] != "command 'commit' is deprecated: use save instead" {
+		t.Errorf("expected a SelectedCommandMsg with a deprecation warning, got %#v", msg)
+	}
+
+	msg = enterCommand(t, m, "git status")
+	if selected, ok := msg.(SelectedCommandMsg); !ok || len(selected.Warnings) != 0 {
+		t.Errorf("expected a SelectedCommandMsg without warnings, got %#v", msg)
+	}
+}
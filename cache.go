@@ -0,0 +1,152 @@
+package bubblecomplete
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MARK: Types and Vars
+
+// completionCache holds the results of completers, so they're not called again on every keystroke
+//
+// Entries expire after the TTL, and the least recently used entries are evicted once the cache
+// is full. A nil cache caches nothing.
+type completionCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// Entries ordered from most to least recently used
+	order *list.List
+	ttl   time.Duration
+	size  int
+}
+
+type cacheEntry struct {
+	key string
+	// The flag or positional argument the entry is for
+	arg         Argument
+	suggestions []Suggestion
+	expires     time.Time
+}
+
+// MARK: Public Functions
+
+// InvalidateCompletions removes the cached completer results for the flag or positional argument with the given name
+//
+// Flags are referenced by their short or long flag and positional arguments by their name.
+// An empty name removes all cached completer results.
+func (m *Model) InvalidateCompletions(name string) {
+	m.completionCache.invalidate(name)
+}
+
+// MARK: Private Functions
+
+func newCompletionCache() *completionCache {
+	return &completionCache{
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// configure sets the TTL and maximum number of entries, evicting entries if the cache is now too big
+func (c *completionCache) configure(ttl time.Duration, size int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+	c.size = size
+	c.evict()
+}
+
+func (c *completionCache) get(key string) ([]Suggestion, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.suggestions, true
+}
+
+func (c *completionCache) put(key string, arg Argument, suggestions []Suggestion) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.ttl <= 0 || c.size <= 0 {
+		return
+	}
+
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	entry := &cacheEntry{key: key, arg: arg, suggestions: suggestions, expires: time.Now().Add(c.ttl)}
+	c.entries[key] = c.order.PushFront(entry)
+	c.evict()
+}
+
+func (c *completionCache) invalidate(name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, element := range c.entries {
+		if name == "" || argumentHasName(element.Value.(*cacheEntry).arg, name) {
+			c.remove(element)
+		}
+	}
+}
+
+// evict removes the least recently used entries until the cache is within its size
+func (c *completionCache) evict() {
+	size := max(c.size, 0)
+	if c.ttl <= 0 {
+		size = 0
+	}
+	for c.order.Len() > size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *completionCache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*cacheEntry).key)
+	c.order.Remove(element)
+}
+
+// completionCacheKey returns the cache key for the completer of the argument at the cursor
+//
+// The key is made up of the argument, the value typed so far and the command entered before it.
+func completionCacheKey(p *parsedInput, arg Argument) string {
+	entered := strings.Join(tokenRaws(p.tokens[:p.cursor.index]), " ")
+	return fmt.Sprintf("%p\x00%s\x00%s", arg, p.cursorValue(), entered)
+}
+
+// argumentHasName returns true if the flag has the given short or long flag, or the positional argument has the given name
+func argumentHasName(arg Argument, name string) bool {
+	switch a := arg.(type) {
+	case *Flag:
+		return a.ShortFlag == name || a.LongFlag == name
+	case *PositionalArgument:
+		return a.Name == name
+	}
+	return false
+}
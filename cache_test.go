@@ -0,0 +1,77 @@
+package bubblecomplete
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompletionCache(t *testing.T) {
+	cache := newCompletionCache()
+	cache.configure(time.Minute, 2)
+
+	flag := &Flag{ShortFlag: "-b", LongFlag: "--branch", Type: StringArgument}
+	arg := &PositionalArgument{Name: "remote", Type: StringArgument}
+
+	cache.put("a", flag, []Suggestion{{Value: "a"}})
+	cache.put("b", arg, []Suggestion{{Value: "b"}})
+	if _, ok := cache.get("a"); !ok {
+		t.Errorf("expected a to be cached")
+	}
+
+	// b is now the least recently used so it's evicted
+	cache.put("c", flag, []Suggestion{{Value: "c"}})
+	if _, ok := cache.get("b"); ok {
+		t.Errorf("expected b to be evicted")
+	}
+
+	cache.invalidate("-b")
+	if _, ok := cache.get("a"); ok {
+		t.Errorf("expected a to be invalidated")
+	}
+	if _, ok := cache.get("c"); ok {
+		t.Errorf("expected c to be invalidated")
+	}
+
+	cache.configure(time.Millisecond, 2)
+	cache.put("d", arg, []Suggestion{{Value: "d"}})
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := cache.get("d"); ok {
+		t.Errorf("expected d to have expired")
+	}
+
+	cache.configure(0, 2)
+	cache.put("e", arg, []Suggestion{{Value: "e"}})
+	if _, ok := cache.get("e"); ok {
+		t.Errorf("expected nothing to be cached with no TTL")
+	}
+}
+
+func TestCompleterCached(t *testing.T) {
+	calls := 0
+	commands := testCommands()
+	push := commands[0].SubCommands[3]
+	push.PositionalArguments[0].Completer = func(partial string, parsed *ParsedCommand) []Suggestion {
+		calls++
+		return []Suggestion{{Value: "origin"}}
+	}
+
+	m, err := New(commands, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{"git push o", "git push or", "git push o", "git push or"} {
+		m.input.SetValue(input)
+		m, _ = m.Update(nil)
+	}
+	if calls != 2 {
+		t.Errorf("expected the completer to be called twice, got %d", calls)
+	}
+
+	m.InvalidateCompletions("remote")
+	m.input.SetValue("git push o")
+	m, _ = m.Update(nil)
+	if calls != 3 {
+		t.Errorf("expected the completer to be called again after invalidating, got %d", calls)
+	}
+}
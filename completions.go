@@ -1,12 +1,26 @@
 package bubblecomplete
 
 import (
-	"fmt"
-	"regexp"
+	"context"
+	"io/fs"
 	"strings"
 	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
 )
 
+// completionsMsg holds the results of a background completer
+type completionsMsg struct {
+	// The ID and value of the input the completer was started for
+	id    int
+	input string
+	// The cache key and argument the completer was started for
+	key         string
+	arg         Argument
+	suggestions []Suggestion
+	err         error
+}
+
 func (m Model) getCompletions() []Completion {
 	if m.input.Value() == "" && !m.showAll {
 		return []Completion{}
@@ -14,19 +28,94 @@ func (m Model) getCompletions() []Completion {
 	var allCompletions []Completion
 
 	if strings.TrimSpace(m.input.Value()) == "" && m.showAll {
-		for _, c := range m.Commands {
-			allCompletions = append(allCompletions, c)
-		}
+		allCompletions = getCommandCompletions(m.Commands, "")
 	} else {
-		allCompletions = getCompletions(m.input.Value(), m.Commands)
+		allCompletions = getCompletions(m.parsed)
 	}
 
-	sortCompletions(&allCompletions)
+	sortCompletions(&allCompletions, m.parsed)
 	uniqueCompletions(&allCompletions)
 	return allCompletions
 }
 
-func sortCompletions(completions *[]Completion) {
+// requestCompletions starts the background completer for the value at the cursor, if there is one
+//
+// Any request still running for the previous input is cancelled.
+func (m *Model) requestCompletions() tea.Cmd {
+	m.cancelPendingCompletions()
+
+	arg := getValueArgument(m.parsed)
+	if arg == nil || arg.getAsyncCompleter() == nil {
+		return nil
+	}
+
+	// Use the cached results if the completer has already been called for the same value
+	key := completionCacheKey(m.parsed, arg)
+	if suggestions, ok := m.completionCache.get(key); ok {
+		m.showSuggestions(suggestions)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancelCompletions = cancel
+	m.completionsPending = true
+
+	completer := arg.getAsyncCompleter()
+	id := m.inputID
+	input := m.input.Value()
+	partial := m.parsed.cursorValue()
+	parsed := newParsedCommandBeforeCursor(m.parsed)
+	request := func() tea.Msg {
+		suggestions, err := completer(ctx, partial, parsed)
+		return completionsMsg{id: id, input: input, key: key, arg: arg, suggestions: suggestions, err: err}
+	}
+	return tea.Batch(request, m.spinner.Tick)
+}
+
+// receiveCompletions shows the results of a background completer, unless the input has changed since it was started
+func (m *Model) receiveCompletions(msg completionsMsg) {
+	// Results of a superseded request are dropped, leaving the request that replaced it pending
+	if msg.id != m.inputID || !m.completionsPending {
+		return
+	}
+	m.cancelPendingCompletions()
+	// The input can change without starting a new request, such as when cycling through the completions
+	if msg.input != m.input.Value() {
+		return
+	}
+	if msg.err != nil {
+		return
+	}
+
+	m.completionCache.put(msg.key, msg.arg, msg.suggestions)
+	m.showSuggestions(msg.suggestions)
+}
+
+// showSuggestions replaces the completions with the suggestions, unless there are none
+func (m *Model) showSuggestions(suggestions []Suggestion) {
+	// Don't replace the completions while they're being cycled through
+	if m.completionHolder != "" {
+		return
+	}
+
+	completions := suggestionCompletions(suggestions)
+	if len(completions) == 0 {
+		return
+	}
+	sortCompletions(&completions, m.parsed)
+	uniqueCompletions(&completions)
+	m.completions = completions
+}
+
+func (m *Model) cancelPendingCompletions() {
+	if m.cancelCompletions != nil {
+		m.cancelCompletions()
+		m.cancelCompletions = nil
+	}
+	m.completionsPending = false
+}
+
+func sortCompletions(completions *[]Completion, p *parsedInput) {
 	allCompletions := *completions
 	for i := 0; i < len(allCompletions); i++ {
 		for j := i + 1; j < len(allCompletions); j++ {
@@ -36,6 +125,9 @@ func sortCompletions(completions *[]Completion) {
 			// Check if the names start with punctuation
 			isPunctI := unicode.IsPunct(rune(nameI[0]))
 			isPunctJ := unicode.IsPunct(rune(nameJ[0]))
+			// Required flags come before the other flags
+			isRequiredI := isRequiredFlag(p, allCompletions[i])
+			isRequiredJ := isRequiredFlag(p, allCompletions[j])
 
 			// If the first name starts with punctuation and the second doesn't, swap them
 			if isPunctI && !isPunctJ {
@@ -43,6 +135,10 @@ func sortCompletions(completions *[]Completion) {
 			} else if !isPunctI && isPunctJ {
 				// Keep the order as is
 				continue
+			} else if isRequiredJ && !isRequiredI {
+				allCompletions[i], allCompletions[j] = allCompletions[j], allCompletions[i]
+			} else if isRequiredI && !isRequiredJ {
+				continue
 			} else if strings.ToLower(nameI) > strings.ToLower(nameJ) {
 				allCompletions[i], allCompletions[j] = allCompletions[j], allCompletions[i]
 			}
@@ -50,6 +146,26 @@ func sortCompletions(completions *[]Completion) {
 	}
 }
 
+// isRequiredFlag returns true if the completion is a required flag that hasn't been entered before the cursor
+//
+// Repeatable flags are still completed once entered, so being in the completions doesn't mean it's missing.
+func isRequiredFlag(p *parsedInput, completion Completion) bool {
+	flag, ok := completion.(*Flag)
+	if !ok || !flag.Required {
+		return false
+	}
+	return p == nil || !p.enteredFlag(flag, p.cursor.flagCount)
+}
+
+// completionDescription returns the description of the completion, marking the required flags still to be entered
+func completionDescription(p *parsedInput, completion Completion) string {
+	description := completion.getDescription()
+	if isRequiredFlag(p, completion) {
+		description += " [required]"
+	}
+	return description
+}
+
 func uniqueCompletions(completions *[]Completion) {
 	keys := make(map[string]bool)
 	list := []Completion{}
@@ -62,438 +178,301 @@ func uniqueCompletions(completions *[]Completion) {
 	*completions = list
 }
 
-func getCompletions(input string, commands []*Command) []Completion {
-	var completions []Completion
-	var globalFlags []*Flag
-
+func getCompletions(p *parsedInput) []Completion {
 	// If the input is empty, return nothing
-	if strings.TrimSpace(input) == "" {
-		return []Completion{}
-	}
-
-	// Split the input into parts so we can handle each part separately
-	parts := splitInput(input)
-	if len(parts) == 0 {
+	if strings.TrimSpace(p.input) == "" {
 		return []Completion{}
 	}
 
-	// If there is only one part and the input doesn't end with a space, we're still typing the first command
-	if len(parts) == 1 && !strings.HasSuffix(input, " ") {
-		// Show all commands that start with the input
-		for _, c := range commands {
-			if strings.HasPrefix(c.Command, parts[0]) {
-				completions = append(completions, c)
-			}
-		}
-		return completions
-	}
+	cursor := p.cursor
 
-	// Otherwise we have at least one command entered so find the final valid command entered
-	var finalCommand *Command
-	commandDepth := 0
-	for _, enteredInput := range parts {
-		for _, c := range commands {
-			// If the command is found in the available commands and we've finished typing then use it
-			if c.Command == enteredInput && strings.Contains(input, fmt.Sprintf("%s ", c.Command)) {
-				finalCommand = c
-				commands = c.SubCommands
-				commandDepth++
-				// If the command has global flags, add them to the global completions
-				for _, flag := range c.Flags {
-					if flag.Persistent && !containsFlag(input, flag) && strings.Contains(input, fmt.Sprintf("%s ", c.Command)) {
-						globalFlags = append(globalFlags, flag)
-					}
-				}
-				break
-			}
-		}
+	// If we're still typing the first command, show all commands that start with the input
+	if cursor.index == 0 {
+		return getCommandCompletions(p.rootCommands, cursor.token.Value)
 	}
 
 	// If we haven't found any command, it must be invalid input so return nothing
-	if finalCommand == nil {
+	if cursor.command == nil {
 		return []Completion{}
 	}
 
 	// From here it's if - return statements
 
-	argParts := parts[commandDepth:]
-	posArgs, flagArgs := splitPositionArgsAndFlags(argParts, finalCommand)
+	// If a flag is waiting for its value, show the values for that flag
+	if cursor.pendingFlag != nil && !isFlagToken(cursor.token) {
+		return getValueCompletions(p, cursor.pendingFlag)
+	}
 
-	// If the final command has subcommands
-	if len(finalCommand.SubCommands) > 0 {
-		completions = handleSubCommandCompletions(finalCommand, parts, commandDepth, input, flagArgs, globalFlags)
-		return completions
+	// If we're typing a flag, show the flags that match it
+	if isFlagToken(cursor.token) {
+		return getFlagCompletions(p)
+	}
+
+	// If the final command has subcommands, which can be entered before any positional arguments
+	if len(cursor.command.SubCommands) > 0 && cursor.positionalCount == 0 {
+		return handleSubCommandCompletions(p)
 	}
 
 	// If the final command has positional arguments
-	if len(finalCommand.PositionalArguments) > 0 {
-		completions = handlePositionalArgumentCompletions(finalCommand, posArgs, flagArgs, input, argParts, globalFlags)
-		return completions
+	if len(cursor.command.PositionalArguments) > 0 {
+		return handlePositionalArgumentCompletions(p)
 	}
 
-	// Otherwise show only the flags
-	completions = append(completions, getFlagCompletions(input, finalCommand, flagArgs, globalFlags)...)
-	return completions
+	// Otherwise show only the flags, if we've not started typing something else
+	if cursor.token.Raw == "" {
+		return getFlagCompletions(p)
+	}
+	return []Completion{}
 }
 
-func handleSubCommandCompletions(
-	cmd *Command,
-	parts []string,
-	depth int,
-	input string,
-	flagArgs []string,
-	globalFlags []*Flag,
-) []Completion {
-	var completions []Completion
+func handleSubCommandCompletions(p *parsedInput) []Completion {
+	completions := getCommandCompletions(p.cursor.command.SubCommands, p.cursor.token.Value)
 
-	// Show subcommands unless there's more parts than expected (i.e. invalid input or flags for parent command)
-	if len(parts) <= depth || (len(parts) == depth+1 && !strings.HasSuffix(input, " ")) {
-		completions = append(completions, getSubCommandCompletions(input, cmd, parts)...)
+	// Commands with positional arguments take a value when it isn't a subcommand. The argument
+	// itself is only shown when there are no values to suggest and no subcommands match
+	if values := getPositionalArgumentCompletions(p); len(values) > 0 {
+		if _, isArgument := values[0].(*PositionalArgument); !isArgument || len(completions) == 0 {
+			completions = append(completions, values...)
+		}
 	}
 
-	// Append any flag completions
-	completions = append(completions, getFlagCompletions(input, cmd, flagArgs, globalFlags)...)
+	// Show the flags too if we haven't started typing the subcommand
+	if p.cursor.token.Raw == "" {
+		completions = append(completions, getFlagCompletions(p)...)
+	}
 	return completions
 }
 
-func handlePositionalArgumentCompletions(
-	cmd *Command,
-	posArgs, flagArgs []string,
-	input string,
-	argParts []string,
-	globalFlags []*Flag,
-) []Completion {
+func handlePositionalArgumentCompletions(p *parsedInput) []Completion {
 	var completions []Completion
 
-	// Show the flag arguments if there are no positional arguments entered
-	if len(posArgs) == 0 {
-		completions = append(completions, getFlagCompletions(input, cmd, flagArgs, globalFlags)...)
+	// Show the flags if there are no positional arguments entered
+	if p.cursor.positionalCount == 0 && p.cursor.token.Raw == "" {
+		completions = append(completions, getFlagCompletions(p)...)
 	}
 
-	// Handle positional argument completions
-	enteringPosArg := (len(posArgs) > 0 || strings.HasSuffix(input, " ")) && len(posArgs) < len(cmd.PositionalArguments)
-	enteringLastPosArg := len(posArgs) == len(cmd.PositionalArguments) && !strings.HasPrefix(argParts[len(argParts)-1], "-")
-	if enteringPosArg || enteringLastPosArg {
-		completions = append(completions, getPositionalArgumentCompletions(input, cmd, posArgs)...)
-		return completions
-	}
+	completions = append(completions, getPositionalArgumentCompletions(p)...)
 
+	// Once every positional argument has been entered, show the remaining flags
+	if len(completions) == 0 && p.cursor.token.Raw == "" {
+		completions = append(completions, getFlagCompletions(p)...)
+	}
 	return completions
 }
 
-func getSubCommandCompletions(input string, finalCommand *Command, parts []string) []Completion {
+func getCommandCompletions(commands []*Command, prefix string) []Completion {
 	completions := []Completion{}
-
-	// If we've started typing, show only subcommands that start with the input
-	if !strings.HasSuffix(input, " ") {
-		for _, command := range finalCommand.SubCommands {
-			if strings.HasPrefix(command.Command, parts[len(parts)-1]) {
-				// Filter out commands that have already been entered
-				if !strings.Contains(input, fmt.Sprintf(" %s ", command.Command)) {
-					completions = append(completions, command)
-				}
-			}
-		}
-		return completions
-	}
-
-	// Otherwise show all subcommands
-	for _, command := range finalCommand.SubCommands {
-		// Filter out commands that have already been entered
-		if !strings.Contains(input, fmt.Sprintf(" %s ", command.Command)) {
-			completions = append(completions, command)
+	for _, c := range commands {
+		if !c.Hidden && c.hasPrefix(prefix) {
+			completions = append(completions, c)
 		}
 	}
-
 	return completions
 }
 
-func getPositionalArgumentCompletions(input string, finalCommand *Command, posArgParts []string) []Completion {
-	completions := []Completion{}
-
-	// If we haven't entered any positional arguments yet, show the first one
-	if len(posArgParts) == 0 {
-		return []Completion{finalCommand.PositionalArguments[0]}
+func getPositionalArgumentCompletions(p *parsedInput) []Completion {
+	// Show the values for the positional argument being entered, if there is one
+	if arg := p.cursor.command.positionalAt(p.cursor.positionalCount); arg != nil {
+		return getValueCompletions(p, arg)
 	}
+	return []Completion{}
+}
 
-	// If we're entering a positional argument value, show only the positional argument for that value
-	if yes, arg := isEnteringPosArgValue(input, finalCommand, posArgParts); yes {
+// getValueCompletions returns the values suggested by the completer of the argument or its type
+// that match the value typed so far, or the argument itself if there's nothing to suggest
+func getValueCompletions(p *parsedInput, arg Argument) []Completion {
+	completer := arg.getCompleter()
+	if completer == nil {
+		completer = typeCompleter(p, arg)
+	}
+	if completer == nil {
 		return []Completion{arg}
 	}
 
-	// Otherwise show the next positional argument if there is one
-	if len(posArgParts) < len(finalCommand.PositionalArguments) {
-		return []Completion{finalCommand.PositionalArguments[len(posArgParts)]}
+	// Only the results of the argument's own completer are cached, as type completers are cheap
+	// and their values, such as the files in a directory, can change at any time
+	var suggestions []Suggestion
+	if arg.getCompleter() != nil {
+		key := completionCacheKey(p, arg)
+		var ok bool
+		if suggestions, ok = p.cache.get(key); !ok {
+			suggestions = completer(p.cursorValue(), newParsedCommandBeforeCursor(p))
+			p.cache.put(key, arg, suggestions)
+		}
+	} else {
+		suggestions = completer(p.cursorValue(), newParsedCommandBeforeCursor(p))
 	}
 
+	completions := suggestionCompletions(suggestions)
+	if len(completions) == 0 {
+		return []Completion{arg}
+	}
 	return completions
 }
 
-func isEnteringPosArgValue(input string, finalCommand *Command, posArgParts []string) (bool, *PositionalArgument) {
-	if len(posArgParts) == 0 {
-		return false, nil
+// getValueArgument returns the flag or positional argument whose value is at the cursor, if any
+func getValueArgument(p *parsedInput) Argument {
+	cursor := p.cursor
+	if cursor.index == 0 || cursor.command == nil {
+		return nil
 	}
 
-	lastArg := posArgParts[len(posArgParts)-1]
-	positionalArgument := finalCommand.PositionalArguments[len(posArgParts)-1]
-
-	// Does the last arg start with a quote?
-	if strings.HasPrefix(lastArg, "\"") || strings.HasPrefix(lastArg, "'") {
-		quote := lastArg[0:1]
-		// If the last arg doesn't end with a quote, we're entering a value
-		if !strings.HasSuffix(lastArg, quote) {
-			return true, positionalArgument
-		}
+	if cursor.pendingFlag != nil && !isFlagToken(cursor.token) {
+		return cursor.pendingFlag
 	}
 
-	if !strings.HasSuffix(input, " ") {
-		return true, positionalArgument
-	}
-
-	return false, nil
-}
-
-func getFlagCompletions(input string, finalCommand *Command, flagArgParts []string, globalFlags []*Flag) []Completion {
-	completions := []Completion{}
-
-	allFlags := append(finalCommand.Flags, globalFlags...)
-
-	// If we haven't entered any flags yet, show all flags
-	if len(flagArgParts) == 0 {
-		for _, a := range allFlags {
-			completions = append(completions, a)
+	if name, _, found := p.cursorInlineFlag(); found {
+		if flag, err := findFlag(p.availableFlags(cursor.depth), name); err == nil {
+			return flag
 		}
-		return completions
+		return nil
 	}
 
-	// If we're entering a flag value, show only the flag for that value
-	if yes, flag := isEnteringFlagValue(input, finalCommand, flagArgParts); yes {
-		return []Completion{flag}
+	if isFlagToken(cursor.token) || p.cursorMaybeSubCommand() {
+		return nil
 	}
 
-	// If we need to enter a flag value, show only the flag for that value
-	if yes, flag := needToEnterFlagValue(finalCommand, flagArgParts); yes {
-		return []Completion{flag}
+	if arg := cursor.command.positionalAt(cursor.positionalCount); arg != nil {
+		return arg
 	}
+	return nil
+}
 
-	// Otherwise if we end with a space, show all flags not yet entered
-	if strings.HasSuffix(input, " ") {
-		for _, flag := range allFlags {
-			if !containsFlag(input, flag) {
-				completions = append(completions, flag)
-			}
-		}
-		return completions
+// typeCompleter returns a completer suggesting values with the completer of the argument type, if it has one
+func typeCompleter(p *parsedInput, arg Argument) CompleterFunc {
+	definition, ok := lookupArgumentType(arg.getType())
+	if !ok || definition.Complete == nil {
+		return nil
 	}
-
-	// Otherwise finally, show completions based on the argument being entered
-	finalPart := flagArgParts[len(flagArgParts)-1]
-	// If the last argument is a combined short flag, only check for the last character flag
-	if !strings.HasPrefix(finalPart, "--") && len(finalPart) > 2 {
-		finalPart = "-" + finalPart[len(finalPart)-1:]
+	return func(partial string, parsed *ParsedCommand) []Suggestion {
+		return definition.Complete(newValueContext(p, arg, parsed), partial)
 	}
-	for _, flag := range allFlags {
-		if strings.HasPrefix(flag.ShortFlag, finalPart) || strings.HasPrefix(flag.LongFlag, finalPart) {
-			// Filter out arguments that have already been entered except for the one we're entering
-			if !containsFlag(input, flag) || (finalPart == flag.ShortFlag || finalPart == flag.LongFlag) {
-				completions = append(completions, flag)
-			}
+}
+
+// completeChoices suggests the choices of the argument that start with the value typed so far
+func completeChoices(ctx ValueContext, partial string) []Suggestion {
+	suggestions := []Suggestion{}
+	for _, choice := range ctx.Argument.getChoices() {
+		if strings.HasPrefix(choice.Value, partial) {
+			suggestions = append(suggestions, Suggestion(choice))
 		}
 	}
-
-	return completions
+	return suggestions
 }
 
-func isEnteringFlagValue(input string, finalCommand *Command, flagArgParts []string) (bool, *Flag) {
-	if len(flagArgParts) == 0 {
-		return false, nil
+// completePaths suggests the files and directories that start with the path typed so far
+//
+// Hidden entries are only suggested once a dot is typed, directories are suffixed with a slash,
+// and only directories are suggested for a DirArgument.
+func completePaths(ctx ValueContext, partial string) []Suggestion {
+	if partial == "~" && ctx.FS == nil {
+		return []Suggestion{{Value: "~/"}}
 	}
 
-	lastArg := flagArgParts[len(flagArgParts)-1]
+	dir, base := "", partial
+	if i := strings.LastIndex(partial, "/"); i != -1 {
+		dir, base = partial[:i+1], partial[i+1:]
+	}
 
-	// Check if we're entering a flag value with a space between the flag and value
-	if len(flagArgParts) >= 2 {
-		lastFlag := flagArgParts[len(flagArgParts)-2]
-		lastValue := lastArg
+	entries, err := readDirPath(ctx, dir)
+	if err != nil {
+		return []Suggestion{}
+	}
 
-		if strings.HasPrefix(lastFlag, "-") && !strings.HasPrefix(lastValue, "-") && !strings.Contains(input, fmt.Sprintf(" %s ", lastValue)) {
-			flagValueToCompare := lastFlag
-			// If the last flag is a short flag, only compare the last character
-			if !strings.HasPrefix(lastFlag, "--") && len(lastFlag) > 2 {
-				flagValueToCompare = "-" + lastFlag[len(lastFlag)-1:]
-			}
-			for _, flag := range finalCommand.Flags {
-				if containsFlag(flagValueToCompare, flag) && flag.Type != BoolArgument {
-					return true, flag
-				}
-			}
+	dirsOnly := ctx.Argument.getType() == DirArgument
+	suggestions := []Suggestion{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+			continue
 		}
-	}
 
-	// Check if we're entering a long flag value with an equals sign between the flag and value
-	if strings.HasPrefix(lastArg, "--") && strings.Contains(lastArg, "=") {
-		if (!stringEndsInQuoteWithoutEquals(lastArg)) || (stringEndsInQuoteWithoutEquals(lastArg) && !strings.HasSuffix(input, " ")) {
-			for _, flag := range finalCommand.Flags {
-				if containsFlag(lastArg, flag) && flag.Type != BoolArgument {
-					return true, flag
-				}
+		isDir := entry.IsDir()
+		if entry.Type()&fs.ModeSymlink != 0 {
+			// Follow symlinks so links to directories can be completed into
+			if info, err := statPath(ctx, dir+name); err == nil {
+				isDir = info.IsDir()
 			}
 		}
-	}
-
-	return false, nil
-}
-
-func needToEnterFlagValue(finalCommand *Command, flagArgParts []string) (bool, *Flag) {
-	lastArgument := flagArgParts[len(flagArgParts)-1]
-	// If the last argument is a combined short flag, only check for the last character flag
-	if !strings.HasPrefix(lastArgument, "--") && len(lastArgument) > 2 {
-		lastArgument = "-" + lastArgument[len(lastArgument)-1:]
-	}
+		if !isDir && (dirsOnly || !suggestFile(ctx, dir+name, name)) {
+			continue
+		}
 
-	for _, a := range finalCommand.Flags {
-		// If the last argument contains a flag and isn't a long flag with an equals sign pattern
-		if containsFlag(lastArgument, a) && !strings.Contains(lastArgument, fmt.Sprintf("%s=", a.LongFlag)) {
-			// Bool arguments don't need a value
-			if a.Type != BoolArgument {
-				return true, a
-			}
+		if isDir {
+			name += "/"
 		}
+		suggestions = append(suggestions, Suggestion{Value: dir + name})
 	}
-
-	return false, nil
+	return suggestions
 }
 
-func stringEndsInQuoteWithoutEquals(s string) bool {
-	if strings.HasSuffix(s, "\"") && !strings.HasSuffix(s, "=\"") {
-		return true
+// suggestFile returns true if the file meets the path options of the argument, so would be valid if chosen
+func suggestFile(ctx ValueContext, path string, name string) bool {
+	options := ctx.Argument.getPathOptions()
+	if options.existence == PathMustNotExist || !matchesFileName(name, options) {
+		return false
 	}
-	if strings.HasSuffix(s, "'") && !strings.HasSuffix(s, "='") {
-		return true
+	if options.writable {
+		info, err := statPath(ctx, path)
+		return err == nil && isWritable(ctx, path, info)
 	}
-	return false
+	return true
 }
 
-func splitPositionArgsAndFlags(argParts []string, command *Command) ([]string, []string) {
-	// For a given input, split the input into flags and their values and positional arguments
-
-	// If the input is empty, return nothing
-	if len(argParts) == 0 {
-		return []string{}, []string{}
-	}
-
-	// If there are no flags, return all positional arguments
-	if len(command.Flags) == 0 {
-		return argParts, []string{}
-	}
-
-	// If there are no positional arguments, return all flags
-	if len(command.PositionalArguments) == 0 {
-		return []string{}, argParts
-	}
-
-	// If there are both positional and flags
-	var positionalArgs []string
-	var flags []string
-	for i := 0; i < len(argParts); i++ {
-		// If the argument is a flag, add it and its value to the flags
-		if strings.HasPrefix(argParts[i], "-") {
-			for _, a := range command.Flags {
-				if containsFlag(argParts[i], a) {
-					// Add the flag
-					flags = append(flags, argParts[i])
-					// If the argument is a boolean, don't check for a value
-					if a.Type == BoolArgument {
-						break
-					}
-					// If we have enough parts left, add the value
-					if i+1 < len(argParts) {
-						flags = append(flags, argParts[i+1])
-						i++
-					}
-				} else {
-					// It's an invalid but still entered flag
-					flags = append(flags, argParts[i])
-				}
-			}
-		} else {
-			// If the argument is not a flag, add it to the positional arguments
-			positionalArgs = append(positionalArgs, argParts[i])
+func suggestionCompletions(suggestions []Suggestion) []Completion {
+	completions := []Completion{}
+	for _, suggestion := range suggestions {
+		if suggestion.Value != "" {
+			completions = append(completions, suggestion)
 		}
 	}
-
-	return positionalArgs, flags
+	return completions
 }
 
-func containsFlag(command string, flag *Flag) bool {
-	command = removeQuotedStrings(command)
-	if flag.ShortFlag != "" && containsShortFlag(command, flag.ShortFlag) {
-		return true
-	}
-	if flag.LongFlag != "" && containsLongFlag(command, flag.LongFlag) {
-		return true
-	}
-	return false
-}
+func getFlagCompletions(p *parsedInput) []Completion {
+	completions := []Completion{}
+	cursor := p.cursor
+	allFlags := p.availableFlags(cursor.depth)
 
-// removeQuotedStrings removes the contents between quotes from a string
-//
-// For example, the input `-m "Hello, world!"` would return `-m ""`
-func removeQuotedStrings(input string) string {
-	var result []rune
-	var hold []rune
-	var quoteChar rune
-	inQuotes := false
-
-	for _, char := range input {
-		if inQuotes {
-			if char == quoteChar {
-				inQuotes = false
-				result = append(result, char)
-			} else {
-				hold = append(hold, char)
+	// If we're not typing a flag, show all flags not yet entered and the repeatable flags
+	if !isFlagToken(cursor.token) {
+		for _, flag := range allFlags {
+			if flag.Hidden || p.excludedFlag(flag, cursor.depth, cursor.flagCount) {
+				continue
 			}
-		} else {
-			if char == '\'' || char == '"' {
-				inQuotes = true
-				quoteChar = char
-				result = append(result, char)
-				hold = []rune{}
-			} else {
-				result = append(result, char)
+			if flag.repeatable() || !p.enteredFlag(flag, cursor.flagCount) {
+				completions = append(completions, flag)
 			}
 		}
+		return completions
 	}
 
-	// If the quotes were not closed properly, return the original input
-	if inQuotes {
-		result = append(result, hold...)
-	}
-
-	return string(result)
-}
-
-func containsShortFlag(command string, flag string) bool {
-	if len(flag) > 0 && flag[0] == '-' {
-		flag = flag[1:]
-	}
-	pattern := fmt.Sprintf(`(^|\s)-[a-zA-Z]*%s[a-zA-Z]*($|\s)`, regexp.QuoteMeta(flag))
-	match, _ := regexp.MatchString(pattern, command)
-	return match
-}
-
-func containsLongFlag(command string, flag string) bool {
-	if command == flag {
-		return true
+	// If we're entering a long flag value with an equals sign, show the values for that flag
+	if name, _, found := p.cursorInlineFlag(); found {
+		if flag, err := findFlag(allFlags, name); err == nil {
+			return getValueCompletions(p, flag)
+		}
+		return completions
 	}
 
-	if strings.Contains(command, fmt.Sprintf(" %s ", flag)) {
-		return true
+	// Otherwise show the flags that start with the flag being entered
+	typed := cursor.token.Value
+	// If the flag is a combined short flag, only check for the last character flag
+	if !strings.HasPrefix(typed, "--") && len([]rune(typed)) > 2 {
+		runes := []rune(typed)
+		typed = "-" + string(runes[len(runes)-1])
 	}
-
-	if strings.Contains(command, fmt.Sprintf("%s=", flag)) {
-		return true
+	for _, flag := range allFlags {
+		if flag.Hidden || p.excludedFlag(flag, cursor.depth, cursor.flagCount) {
+			continue
+		}
+		if strings.HasPrefix(flag.ShortFlag, typed) || strings.HasPrefix(flag.LongFlag, typed) {
+			// Filter out flags that have already been entered except for repeatable flags and the one we're entering
+			if flag.repeatable() || !p.enteredFlag(flag, cursor.flagCount) || typed == flag.ShortFlag || typed == flag.LongFlag {
+				completions = append(completions, flag)
+			}
+		}
 	}
 
-	return false
+	return completions
 }
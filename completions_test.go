@@ -1,194 +1,626 @@
 package bubblecomplete
 
-import "testing"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
 
-func TestContainsLongFlag(t *testing.T) {
-	command := "This is a test command --flag1 --flag2=value --anotherFlag='--flag3' --testing '--flag4'"
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
 
-	flag := "--flag1"
-	expected := true
-	result := containsLongFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsLongFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+func testCommands() []*Command {
+	return []*Command{
+		{
+			Command: "git",
+			SubCommands: []*Command{
+				{
+					Command: "stash",
+					SubCommands: []*Command{
+						{Command: "pop"},
+						{Command: "apply"},
+					},
+				},
+				{Command: "status"},
+				{
+					Command: "commit",
+					Flags: []*Flag{
+						{ShortFlag: "-m", LongFlag: "--message", Type: StringArgument},
+						{ShortFlag: "-a", LongFlag: "--all", Type: BoolArgument},
+						{LongFlag: "--amend", Type: BoolArgument},
+					},
+				},
+				{
+					Command: "push",
+					PositionalArguments: []*PositionalArgument{
+						{Name: "remote", Type: StringArgument},
+						{Name: "branch", Type: StringArgument},
+					},
+					Flags: []*Flag{
+						{ShortFlag: "-f", LongFlag: "--force", Type: BoolArgument},
+					},
+				},
+			},
+			Flags: []*Flag{
+				{LongFlag: "--version", Type: BoolArgument},
+				{LongFlag: "--help", Type: BoolArgument, Persistent: true},
+			},
+		},
+		{Command: "go"},
 	}
+}
+
+func completionNames(completions []Completion) []string {
+	names := []string{}
+	for _, c := range completions {
+		names = append(names, c.getName())
+	}
+	return names
+}
+
+func TestGetCompletions(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"g", []string{"git", "go"}},
+		{"gi", []string{"git"}},
+		{"git ", []string{"stash", "status", "commit", "push", "--version", "--help"}},
+		{"git st", []string{"stash", "status"}},
+		{"git stash", []string{"stash"}},
+		{"git --help ", []string{"stash", "status", "commit", "push", "--version"}},
+		{"git --help stash p", []string{"pop"}},
+		{"git commit --", []string{"-m --message", "-a --all", "--amend", "--help"}},
+		{"git commit --am", []string{"--amend"}},
+		{"git commit -a ", []string{"-m --message", "--amend", "--help"}},
+		{"git commit -am", []string{"-m --message"}},
+		{"git commit -m ", []string{"-m --message"}},
+		{"git commit -m \"hello wor", []string{"-m --message"}},
+		{"git commit --message=\"hello", []string{"-m --message"}},
+		{"git commit -m \"-a\" ", []string{"-a --all", "--amend", "--help"}},
+		{"git commit -m 'hello' -", []string{"-a --all", "--amend", "--help"}},
+		{"git push ", []string{"-f --force", "--help", "remote"}},
+		{"git push ori", []string{"remote"}},
+		{"git push origin ", []string{"branch"}},
+		{"git push origin main ", []string{"-f --force", "--help"}},
+		{"nope ", []string{}},
+	}
+
+	for _, c := range cases {
+		result := completionNames(getCompletions(parseInput(c.input, testCommands())))
+		if !slices.Equal(result, c.expected) {
+			t.Errorf("getCompletions(%q) == %q, expected %q", c.input, result, c.expected)
+		}
+	}
+}
+
+func TestGetCompletionsCompleter(t *testing.T) {
+	branches := func(partial string, parsed *ParsedCommand) []Suggestion {
+		suggestions := []Suggestion{}
+		for _, branch := range []string{"main", "my feature", "release"} {
+			if strings.HasPrefix(branch, partial) {
+				suggestions = append(suggestions, Suggestion{Value: branch, Description: parsed.String("remote")})
+			}
+		}
+		return suggestions
+	}
+
+	commands := testCommands()
+	push := commands[0].SubCommands[3]
+	push.PositionalArguments[1].Completer = branches
+	push.Flags = append(push.Flags, &Flag{LongFlag: "--branch", Type: StringArgument, Completer: branches})
+
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"git push origin ", []string{"main", "my feature", "release"}},
+		{"git push origin m", []string{"main", "my feature"}},
+		{"git push origin x", []string{"branch"}},
+		{"git push --branch ", []string{"main", "my feature", "release"}},
+		{"git push --branch=r", []string{"release"}},
+	}
+
+	for _, c := range cases {
+		result := completionNames(getCompletions(parseInput(c.input, commands)))
+		if !slices.Equal(result, c.expected) {
+			t.Errorf("getCompletions(%q) == %q, expected %q", c.input, result, c.expected)
+		}
+	}
+
+	completions := getCompletions(parseInput("git push origin ", commands))
+	if completions[0].getDescription() != "origin" {
+		t.Errorf("expected the completer to receive the parsed remote, got %q", completions[0].getDescription())
+	}
+	if completions[1].getAutocomplete() != "\"my feature\"" {
+		t.Errorf("expected the suggestion to be quoted, got %q", completions[1].getAutocomplete())
+	}
+}
 
-	flag = "--flag2"
-	expected = true
-	result = containsLongFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsLongFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+// runCmd runs the command and any batched commands, returning the messages other than spinner ticks
+func runCmd(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
 	}
+	var msgs []tea.Msg
+	switch msg := cmd().(type) {
+	case tea.BatchMsg:
+		for _, c := range msg {
+			msgs = append(msgs, runCmd(c)...)
+		}
+	case spinner.TickMsg:
+	default:
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
 
-	flag = "--flag3"
-	expected = false
-	result = containsLongFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsLongFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+func TestAsyncCompleter(t *testing.T) {
+	commands := testCommands()
+	push := commands[0].SubCommands[3]
+	push.PositionalArguments[1].AsyncCompleter = func(ctx context.Context, partial string, parsed *ParsedCommand) ([]Suggestion, error) {
+		if partial == "slow" {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return []Suggestion{{Value: partial + "-branch"}}, nil
 	}
 
-	flag = "--flag4"
-	expected = false
-	result = containsLongFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsLongFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+	m, err := New(commands, 100)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	command = "--flag2"
+	var cmd tea.Cmd
+	m.input.SetValue("git push origin slow")
+	m, cmd = m.Update(nil)
+	if !m.completionsPending || !strings.Contains(m.View(), "Loading completions...") {
+		t.Errorf("expected completions to be pending")
+	}
+
+	slow := make(chan []tea.Msg)
+	go func(cmd tea.Cmd) {
+		slow <- runCmd(cmd)
+	}(cmd)
 
-	flag = "--flag1"
-	expected = false
-	result = containsLongFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsLongFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+	// Changing the input cancels the slow request, and its results are dropped
+	m.input.SetValue("git push origin ma")
+	m, cmd = m.Update(nil)
+	for _, msg := range <-slow {
+		m, _ = m.Update(msg)
 	}
+	if !m.completionsPending || !slices.Equal(completionNames(m.completions), []string{"branch"}) {
+		t.Errorf("expected the superseded results to be dropped, got %q", completionNames(m.completions))
+	}
+
+	for _, msg := range runCmd(cmd) {
+		m, _ = m.Update(msg)
+	}
+	if m.completionsPending || !slices.Equal(completionNames(m.completions), []string{"ma-branch"}) {
+		t.Errorf("expected the completions to be %q, got %q", []string{"ma-branch"}, completionNames(m.completions))
+	}
+}
+
+func TestAsyncCompleterCancelled(t *testing.T) {
+	commands := testCommands()
+	push := commands[0].SubCommands[3]
+	push.PositionalArguments[1].AsyncCompleter = func(ctx context.Context, partial string, parsed *ParsedCommand) ([]Suggestion, error) {
+		<-ctx.Done()
+		return []Suggestion{{Value: partial + "-branch"}}, nil
+	}
+
+	for _, key := range []tea.KeyMsg{{Type: tea.KeyTab}, {Type: tea.KeyBackspace}} {
+		m, err := New(commands, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var cmd tea.Cmd
+		m.input.SetValue("git push origin s")
+		m, cmd = m.Update(nil)
+		if !m.completionsPending {
+			t.Fatalf("expected completions to be pending")
+		}
+
+		// The completer only returns once its context is cancelled
+		done := make(chan []tea.Msg)
+		go func(cmd tea.Cmd) {
+			done <- runCmd(cmd)
+		}(cmd)
 
-	flag = "--flag2"
-	expected = true
-	result = containsLongFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsLongFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+		if key.Type == tea.KeyBackspace {
+			m.input.SetValue("s")
+			m.input.CursorEnd()
+		}
+		m, _ = m.Update(key)
+		if m.completionsPending || strings.Contains(m.View(), "Loading completions...") {
+			t.Errorf("expected %s to stop waiting for the completions", key)
+		}
+
+		var msgs []tea.Msg
+		select {
+		case msgs = <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("expected %s to cancel the completer", key)
+		}
+		for _, msg := range msgs {
+			m, _ = m.Update(msg)
+		}
+		if m.completionsPending || slices.Contains(completionNames(m.completions), "s-branch") {
+			t.Errorf("expected the results to be dropped after %s, got %q", key, completionNames(m.completions))
+		}
 	}
 }
 
-func TestContainsShortFlag(t *testing.T) {
-	command := "This is a test command -f -m \"Added flag -x\" --fsomething '-p' -yz"
+func TestGetCompletionsChoices(t *testing.T) {
+	formats := []Choice{
+		{Value: "json", Description: "JSON output"},
+		{Value: "yaml", Description: "YAML output"},
+		{Value: "jsonl", Description: "JSON lines output"},
+	}
+	commands := []*Command{
+		{
+			Command: "export",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "format", Type: ChoiceArgument, Choices: formats},
+			},
+			Flags: []*Flag{
+				{ShortFlag: "-o", LongFlag: "--output", Type: ChoiceArgument, Choices: formats},
+			},
+		},
+	}
 
-	flag := "-f"
-	expected := true
-	result := containsShortFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsShortFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"export -o ", []string{"json", "yaml", "jsonl"}},
+		{"export -o j", []string{"json", "jsonl"}},
+		{"export --output=", []string{"json", "yaml", "jsonl"}},
+		{"export --output=y", []string{"yaml"}},
+		{"export --output=x", []string{"-o --output"}},
+		{"export y", []string{"yaml"}},
 	}
 
-	flag = "-m"
-	expected = true
-	result = containsShortFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsShortFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+	for _, c := range cases {
+		result := completionNames(getCompletions(parseInput(c.input, commands)))
+		if !slices.Equal(result, c.expected) {
+			t.Errorf("getCompletions(%q) == %q, expected %q", c.input, result, c.expected)
+		}
 	}
+}
 
-	flag = "-x"
-	expected = false
-	result = containsShortFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsShortFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+func TestGetCompletionsPaths(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"notes.txt", "my file.txt", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"docs", "downloads"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatal(err)
+		}
 	}
+	t.Setenv("HOME", dir)
 
-	flag = "-p"
-	expected = false
-	result = containsShortFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsShortFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+	commands := []*Command{
+		{
+			Command: "cp",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileDirArgument},
+				{Name: "destination", Type: DirArgument},
+			},
+		},
 	}
 
-	flag = "-y"
-	expected = true
-	result = containsShortFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsShortFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"cp " + dir + "/", []string{dir + "/docs/", dir + "/downloads/", dir + "/my file.txt", dir + "/notes.txt"}},
+		{"cp " + dir + "/d", []string{dir + "/docs/", dir + "/downloads/"}},
+		{"cp " + dir + "/.", []string{dir + "/.hidden"}},
+		{"cp ~/n", []string{"~/notes.txt"}},
+		{"cp ~", []string{"~/"}},
+		{"cp notes.txt ~/", []string{"~/docs/", "~/downloads/"}},
 	}
 
-	flag = "-z"
-	expected = true
-	result = containsShortFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsShortFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+	for _, c := range cases {
+		p := parseInput(c.input, commands)
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
 	}
 
-	command = "-f"
+	completions := getCompletions(parseInput("cp ~/my", commands))
+	if len(completions) != 1 || completions[0].getAutocomplete() != "\"~/my file.txt\"" {
+		t.Errorf("expected the path with a space to be quoted, got %v", completionNames(completions))
+	}
+}
 
-	flag = "-f"
-	expected = true
-	result = containsShortFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsShortFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+func TestCommandAliases(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "git",
+			SubCommands: []*Command{
+				{Command: "commit", Aliases: []string{"ci"}},
+				{
+					Command: "checkout",
+					Aliases: []string{"co"},
+					PositionalArguments: []*PositionalArgument{
+						{Name: "branch", Type: StringArgument, Required: true},
+					},
+					Flags: []*Flag{
+						{ShortFlag: "-b", Type: BoolArgument},
+					},
+				},
+			},
+		},
 	}
 
-	flag = "-l"
-	expected = false
-	result = containsShortFlag(command, flag)
-	if result != expected {
-		t.Errorf("containsShortFlag(%q, %q) == %t, expected %t", command, flag, result, expected)
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"git c", []string{"checkout", "commit"}},
+		{"git co", []string{"checkout", "commit"}},
+		{"git ci", []string{"commit"}},
+		{"git co -", []string{"-b"}},
+	}
+	for _, c := range cases {
+		p := parseInput(c.input, commands)
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
+	}
+
+	p := parseInput("git co main", commands)
+	if err := validateCommandInput(p); err != nil {
+		t.Errorf("expected the alias to be valid, got %v", err)
+	}
+	if cmd := newParsedCommand(p).Command(); cmd.Command != "checkout" {
+		t.Errorf("Command() == %q, expected checkout", cmd.Command)
+	}
+
+	if title := completionTitle(commands[0].SubCommands[1]); !strings.HasPrefix(title, "checkout ") || !strings.Contains(title, "co") {
+		t.Errorf("completionTitle() == %q, expected the alias next to the command", title)
 	}
 }
 
-func TestStringEndsInQuoteWithoutEquals(t *testing.T) {
-	input := "\"This is a test\""
-	expected := true
-	result := stringEndsInQuoteWithoutEquals(input)
-	if result != expected {
-		t.Errorf("stringEndsInQuoteWithoutEquals(%q) == %t, expected %t", input, result, expected)
+func TestHiddenAndDeprecated(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "git",
+			SubCommands: []*Command{
+				{Command: "commit"},
+				{Command: "checkout", Deprecated: "use switch instead"},
+				{Command: "cherry", Hidden: true},
+			},
+			Flags: []*Flag{
+				{LongFlag: "--verbose", Type: BoolArgument},
+				{LongFlag: "--debug", Type: BoolArgument, Hidden: true},
+				{LongFlag: "--dry", Type: BoolArgument, Deprecated: "use --verbose instead"},
+			},
+		},
+	}
+
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"git c", []string{"checkout", "commit"}},
+		{"git ch", []string{"checkout"}},
+		{"git --", []string{"--dry", "--verbose"}},
+		{"git --d", []string{"--dry"}},
+	}
+	for _, c := range cases {
+		p := parseInput(c.input, commands)
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
+	}
+
+	for _, input := range []string{"git cherry", "git --debug", "git checkout", "git --dry"} {
+		if err := validateCommandInput(parseInput(input, commands)); err != nil {
+			t.Errorf("validateCommandInput(%q) == %v, expected nil", input, err)
+		}
 	}
 
-	input = "'This is a test'"
-	expected = true
-	result = stringEndsInQuoteWithoutEquals(input)
-	if result != expected {
-		t.Errorf("stringEndsInQuoteWithoutEquals(%q) == %t, expected %t", input, result, expected)
+	warnings := deprecationWarnings(parseInput("git --dry checkout", commands))
+	expected := []string{"command 'checkout' is deprecated: use switch instead", "flag '--dry' is deprecated: use --verbose instead"}
+	if !slices.Equal(warnings, expected) {
+		t.Errorf("deprecationWarnings() == %q, expected %q", warnings, expected)
 	}
+}
+
+func TestFlagGroupCompletions(t *testing.T) {
+	commands := testFlagGroupCommands()
 
-	input = "\"This is a test\"="
-	expected = false
-	result = stringEndsInQuoteWithoutEquals(input)
-	if result != expected {
-		t.Errorf("stringEndsInQuoteWithoutEquals(%q) == %t, expected %t", input, result, expected)
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"export --json --", []string{"--output", "--password", "--stdout", "--user"}},
+		{"export --stdout --", []string{"--csv", "--json", "--password", "--user", "--yaml"}},
+		{"export --yaml ", []string{"--output", "--password", "--stdout", "--user"}},
+		{"export --", []string{"--csv", "--json", "--output", "--password", "--stdout", "--user", "--yaml"}},
+	}
+	for _, c := range cases {
+		p := parseInput(c.input, commands)
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
+	}
+}
+
+func TestRequiredFlagCompletions(t *testing.T) {
+	commands := testRequiredFlagCommands()
+
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"deploy --", []string{"-p --project", "--tls-cert", "--tls-key", "--verbose"}},
+		{"deploy --t", []string{"--tls-cert", "--tls-key"}},
+		{"deploy -p web --", []string{"--tls-cert", "--tls-key", "--verbose"}},
+		{"deploy ", []string{"status", "-p --project", "--tls-cert", "--tls-key", "--verbose"}},
+	}
+	for _, c := range cases {
+		p := parseInput(c.input, commands)
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
 	}
 
-	input = "'This is a test'="
-	expected = false
-	result = stringEndsInQuoteWithoutEquals(input)
-	if result != expected {
-		t.Errorf("stringEndsInQuoteWithoutEquals(%q) == %t, expected %t", input, result, expected)
+	project := commands[0].Flags[0]
+	if description := completionDescription(parseInput("deploy ", commands), project); !strings.HasSuffix(description, "[required]") {
+		t.Errorf("completionDescription() == %q, expected the flag marked as required", description)
 	}
 
-	input = "'This is a test='"
-	expected = false
-	result = stringEndsInQuoteWithoutEquals(input)
-	if result != expected {
-		t.Errorf("stringEndsInQuoteWithoutEquals(%q) == %t, expected %t", input, result, expected)
+	// Repeatable flags are still completed once entered, but are no longer required
+	tags := []*Command{
+		{
+			Command: "build",
+			Flags: []*Flag{
+				{LongFlag: "--arch", Type: StringArgument},
+				{LongFlag: "--tag", Type: StringArgument, Required: true, Repeatable: true},
+			},
+		},
+	}
+	tag := tags[0].Flags[1]
+	tagCases := []struct {
+		input    string
+		expected []string
+		required bool
+	}{
+		{"build --", []string{"--tag", "--arch"}, true},
+		{"build --tag v1 --", []string{"--arch", "--tag"}, false},
+	}
+	for _, c := range tagCases {
+		p := parseInput(c.input, tags)
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
+		description := completionDescription(p, tag)
+		if required := strings.HasSuffix(description, "[required]"); required != c.required {
+			t.Errorf("completionDescription(%q) == %q, expected required %t", c.input, description, c.required)
+		}
 	}
 }
 
-func TestRemoveQuotedStrings(t *testing.T) {
-	input := "git stash pop"
-	expected := "git stash pop"
-	result := removeQuotedStrings(input)
-	if result != expected {
-		t.Errorf("removeQuotedStrings(%q) == %q, expected %q", input, result, expected)
+func TestRepeatableFlagCompletions(t *testing.T) {
+	commands := testRepeatableFlagCommands()
+
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"build -v -t a --output x --", []string{"--port", "-t --tag", "-v --verbose"}},
+		{"build -vv", []string{"-v --verbose"}},
+		{"build --port 80 ", []string{"--output", "--port", "-t --tag", "-v --verbose"}},
+	}
+	for _, c := range cases {
+		p := parseInput(c.input, commands)
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
 	}
+}
 
-	input = "git commit -m \"hello\" --amend"
-	expected = "git commit -m \"\" --amend"
-	result = removeQuotedStrings(input)
-	if result != expected {
-		t.Errorf("removeQuotedStrings(%q) == %q, expected %q", input, result, expected)
+func TestSubCommandsAndPositionals(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "docker",
+			SubCommands: []*Command{
+				{Command: "image", SubCommands: []*Command{{Command: "ls"}}},
+				{Command: "info"},
+			},
+			PositionalArguments: []*PositionalArgument{
+				{Name: "image", Type: ChoiceArgument, Required: true, Choices: []Choice{{Value: "nginx"}, {Value: "redis"}}},
+				{Name: "tag", Type: StringArgument},
+			},
+			Flags: []*Flag{
+				{LongFlag: "--rm", Type: BoolArgument},
+			},
+		},
 	}
 
-	input = "cp \"/home/me/file.txt\" \"/home/me\""
-	expected = "cp \"\" \"\""
-	result = removeQuotedStrings(input)
-	if result != expected {
-		t.Errorf("removeQuotedStrings(%q) == %q, expected %q", input, result, expected)
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"docker ", []string{"image", "info", "nginx", "redis", "--rm"}},
+		{"docker i", []string{"image", "info"}},
+		{"docker n", []string{"nginx"}},
+		{"docker nginx ", []string{"tag"}},
+		{"docker image ", []string{"ls"}},
+	}
+	for _, c := range cases {
+		p := parseInput(c.input, commands)
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
 	}
 
-	input = "cat 'my/file'"
-	expected = "cat ''"
-	result = removeQuotedStrings(input)
-	if result != expected {
-		t.Errorf("removeQuotedStrings(%q) == %q, expected %q", input, result, expected)
+	valueArguments := []struct {
+		input    string
+		expected string
+	}{
+		{"docker ", ""},
+		{"docker i", ""},
+		{"docker n", "image"},
+		{"docker nginx ", "tag"},
+	}
+	for _, c := range valueArguments {
+		name := ""
+		if arg := getValueArgument(parseInput(c.input, commands)); arg != nil {
+			name = arg.getName()
+		}
+		if name != c.expected {
+			t.Errorf("getValueArgument(%q) == %q, expected %q", c.input, name, c.expected)
+		}
 	}
 
-	input = "git commit -m \"some quote' --amend"
-	expected = "git commit -m \"some quote' --amend"
-	result = removeQuotedStrings(input)
-	if result != expected {
-		t.Errorf("removeQuotedStrings(%q) == %q, expected %q", input, result, expected)
+	validation := []struct {
+		input    string
+		expected string
+	}{
+		{"docker nginx latest", ""},
+		{"docker image ls", ""},
+		{"docker info", ""},
+		{"docker", "missing positional argument: image"},
+		{"docker postgres", "invalid value 'postgres' for argument: image (valid choices: nginx, redis)"},
+	}
+	for _, c := range validation {
+		err := validateCommandInput(parseInput(c.input, commands))
+		if (err == nil && c.expected != "") || (err != nil && err.Error() != c.expected) {
+			t.Errorf("validateCommandInput(%q) == %v, expected %q", c.input, err, c.expected)
+		}
 	}
 
-	input = "cat '\"'\" --help"
-	expected = "cat ''\" --help"
-	result = removeQuotedStrings(input)
-	if result != expected {
-		t.Errorf("removeQuotedStrings(%q) == %q, expected %q", input, result, expected)
+	result := newParsedCommand(parseInput("docker nginx latest", commands))
+	if result.Command().Command != "docker" || result.String("image") != "nginx" || result.String("tag") != "latest" {
+		t.Errorf("newParsedCommand() == %+v, expected the positional arguments of docker", result)
 	}
 }
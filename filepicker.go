@@ -0,0 +1,130 @@
+package bubblecomplete
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/filepicker"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// MARK: Private Functions
+
+// openFilePicker opens the file picker if a path is being entered for a file or directory argument
+//
+// The picker browses the OS filesystem, so isn't opened when the model has an FS. Returns false
+// if the picker wasn't opened.
+func (m *Model) openFilePicker() (tea.Cmd, bool) {
+	if m.FS != nil {
+		return nil, false
+	}
+
+	p := m.parseInput(m.input.Value())
+	arg := getValueArgument(p)
+	if arg == nil || !isPathArgument(arg.getType()) {
+		return nil, false
+	}
+	ctx := newValueContext(p, arg, nil)
+
+	picker := filepicker.New()
+	picker.CurrentDirectory = filePickerDir(ctx, p.cursorValue())
+	picker.AutoHeight = false
+	picker.Height = m.FilePickerRows
+	picker.FileAllowed = arg.getType() != DirArgument
+	picker.DirAllowed = arg.getType() != FileArgument
+	// The picker's AllowedTypes are case sensitive, so picked files are checked like they're validated instead
+	// Escape closes the picker rather than going back a directory
+	picker.KeyMap.Back = key.NewBinding(key.WithKeys("h", "backspace", "left"), key.WithHelp("h", "back"))
+
+	m.CloseCompletions()
+	m.cancelPendingCompletions()
+	m.filePicker = picker
+	m.pickingPath = true
+	m.pickerErr = nil
+	m.pickerPretext = m.input.Value()[:p.replaceStart()]
+	m.pickerArg = arg
+	return picker.Init(), true
+}
+
+// updateFilePicker passes the message to the open file picker, inserting the path once one is picked
+func (m Model) updateFilePicker(msg tea.Msg) (Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok && (keyMsg.String() == "esc" || key.Matches(keyMsg, m.FilePickerKey)) {
+		m.pickingPath = false
+		return m, nil
+	}
+
+	if _, ok := msg.(tea.KeyMsg); ok {
+		m.pickerErr = nil
+	}
+
+	var cmd tea.Cmd
+	m.filePicker.Path = ""
+	m.filePicker, cmd = m.filePicker.Update(msg)
+	if m.filePicker.Path == "" {
+		return m, cmd
+	}
+
+	// Files without an allowed name can't be picked, so the picker stays open with the reason
+	ctx := newValueContext(m.parseInput(m.pickerPretext), m.pickerArg, nil)
+	picked := m.filePicker.Path
+	if info, err := os.Stat(picked); err == nil && !info.IsDir() {
+		if err := checkFileName(ctx, picked); err != nil {
+			m.pickerErr = err
+			return m, cmd
+		}
+	}
+
+	m.pickingPath = false
+	m.input.SetValue(m.pickerPretext + quoteValue(filePickerResult(ctx, picked)))
+	m.input.CursorEnd()
+	return m, tea.Batch(cmd, m.inputChanged())
+}
+
+func (m Model) filePickerRender() string {
+	header := lg.Foreground(pink).Bold(true).Render(m.filePicker.CurrentDirectory)
+	rows := []string{header, strings.TrimRight(m.filePicker.View(), "\n")}
+	if m.pickerErr != nil {
+		rows = append(rows, pickerErrorStyle.Render(m.pickerErr.Error()))
+	}
+	picker := completionsBoxStyle.Render(lipgloss.JoinVertical(lipgloss.Left, rows...))
+
+	if m.CompletionsPosition == PositionAbove {
+		return picker + "\n" + m.inputView()
+	}
+	return m.inputView() + "\n" + picker
+}
+
+// filePickerDir returns the absolute directory the file picker starts in, the directory of the
+// path typed so far if it exists or the working directory otherwise
+func filePickerDir(ctx ValueContext, partial string) string {
+	dir := ""
+	if i := strings.LastIndex(partial, "/"); i != -1 {
+		dir = partial[:i+1]
+	}
+
+	start := osPath(ctx, dir)
+	if info, err := os.Stat(start); err != nil || !info.IsDir() {
+		start = osPath(ctx, "")
+	}
+	if abs, err := filepath.Abs(start); err == nil {
+		return abs
+	}
+	return start
+}
+
+// filePickerResult returns the picked path relative to the working directory if it's inside it,
+// or the absolute path otherwise
+func filePickerResult(ctx ValueContext, picked string) string {
+	base, err := filepath.Abs(osPath(ctx, ""))
+	if err != nil {
+		return picked
+	}
+	rel, err := filepath.Rel(base, picked)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return picked
+	}
+	return rel
+}
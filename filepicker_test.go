@@ -0,0 +1,122 @@
+package bubblecomplete
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// updateAll updates the model with the message and then the messages of any commands it returns
+func updateAll(m Model, msg tea.Msg) Model {
+	m, cmd := m.Update(msg)
+	if cmd != nil {
+		if next := cmd(); next != nil {
+			if _, ok := next.(tea.BatchMsg); !ok {
+				m = updateAll(m, next)
+			}
+		}
+	}
+	return m
+}
+
+func TestFilePicker(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "my docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"my docs/report.csv", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commands := []*Command{
+		{
+			Command: "open",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileArgument, Required: true, Extensions: []string{".csv"}},
+			},
+		},
+	}
+	m, err := New(commands, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.WorkingDir = dir
+	m.input.SetValue("open ")
+
+	m = updateAll(m, tea.KeyMsg{Type: tea.KeyCtrlO})
+	if !m.pickingPath || m.filePicker.CurrentDirectory != dir {
+		t.Fatalf("expected the file picker to open in %s, got %s", dir, m.filePicker.CurrentDirectory)
+	}
+
+	// Files without an allowed extension can't be picked
+	m = updateAll(m, tea.KeyMsg{Type: tea.KeyDown})
+	m = updateAll(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.pickingPath {
+		t.Fatalf("expected notes.txt not to be picked")
+	}
+	if view := m.filePickerRender(); !strings.Contains(view, "invalid file name 'notes.txt' for argument: file") {
+		t.Errorf("expected the file picker to show why notes.txt can't be picked, got %q", view)
+	}
+
+	m = updateAll(m, tea.KeyMsg{Type: tea.KeyUp})
+	if m.pickerErr != nil {
+		t.Errorf("expected the error to be cleared by the next key press, got %v", m.pickerErr)
+	}
+	m = updateAll(m, tea.KeyMsg{Type: tea.KeyRight})
+	m = updateAll(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.pickingPath {
+		t.Fatalf("expected the file picker to close once a file is picked")
+	}
+
+	expected := "open \"my docs/report.csv\""
+	if m.input.Value() != expected || m.validCommand != nil {
+		t.Errorf("picked input == %q, %v, expected %q to be valid", m.input.Value(), m.validCommand, expected)
+	}
+}
+
+func TestFilePickerExtensionCase(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "DATA.CSV"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := []*Command{
+		{
+			Command: "open",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileArgument, Required: true, Extensions: []string{".csv"}},
+			},
+		},
+	}
+	m, err := New(commands, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.WorkingDir = dir
+	m.input.SetValue("open ")
+
+	// Extensions match regardless of case, like they do when validated
+	m = updateAll(m, tea.KeyMsg{Type: tea.KeyCtrlO})
+	m = updateAll(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.pickingPath || m.input.Value() != "open DATA.CSV" {
+		t.Errorf("picked input == %q, expected DATA.CSV to be picked", m.input.Value())
+	}
+}
+
+func TestFilePickerNotOpened(t *testing.T) {
+	m, err := New(testCommands(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.input.SetValue("git commit -m ")
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlO})
+	if m.pickingPath {
+		t.Errorf("expected the file picker not to open for a string argument")
+	}
+}
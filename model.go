@@ -1,12 +1,21 @@
 package bubblecomplete
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
+	"unicode"
 
+	"github.com/charmbracelet/bubbles/filepicker"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/termenv"
 )
@@ -18,12 +27,23 @@ type Model struct {
 
 	input     textinput.Model
 	lastInput string
+	parsed    *parsedInput
+	// The paths in the last input, styled by whether they exist
+	pathHighlights []pathHighlight
 
 	// ---- Commands ----
 
 	// The commands available
 	Commands     []*Command
 	validCommand error
+	middleware   []Middleware
+
+	// ---- Filesystem ----
+
+	// The filesystem paths are validated and completed against, the OS filesystem if nil
+	FS fs.FS
+	// The directory relative paths are resolved from, the current directory if empty
+	WorkingDir string
 
 	// ---- Completions ----
 
@@ -32,6 +52,24 @@ type Model struct {
 	completionHolder string
 	showAll          bool
 
+	// The ID of the current input, used to drop the results of superseded background completions
+	inputID            int
+	cancelCompletions  context.CancelFunc
+	completionsPending bool
+	spinner            spinner.Model
+	completionCache    *completionCache
+
+	// ---- File Picker ----
+
+	filePicker  filepicker.Model
+	pickingPath bool
+	// The input before the path being picked, which the picked path is appended to
+	pickerPretext string
+	// The flag or positional argument the path is being picked for
+	pickerArg Argument
+	// Why the last path picked couldn't be used, shown until the next key press
+	pickerErr error
+
 	// ---- History ----
 
 	// Slice that holds the history of commands
@@ -61,6 +99,12 @@ type Model struct {
 	ValidCommandStyle lipgloss.Style
 	// The text style for invalid commands
 	InvalidCommandStyle lipgloss.Style
+	// The text style for paths that are the start of an existing path
+	PathPrefixStyle lipgloss.Style
+	// The text style for paths that exist
+	PathExistsStyle lipgloss.Style
+	// The text style for paths that aren't valid for their argument
+	PathInvalidStyle lipgloss.Style
 	// Whether to show different border styles to indicate scrolling
 	ShowBorderScroll bool
 	// Whether to show the horizontal scrollbar to indicate scrolling
@@ -69,6 +113,14 @@ type Model struct {
 	CompletionsPosition Position
 	// The number of rows to show in the completions
 	CompletionRows int
+	// How long the results of completers are cached for, 0 to not cache them
+	CompletionCacheTTL time.Duration
+	// The maximum number of completer results to cache
+	CompletionCacheSize int
+	// The key that opens the file picker when entering a path, an empty binding to disable it
+	FilePickerKey key.Binding
+	// The number of rows to show in the file picker
+	FilePickerRows int
 }
 
 type Completion interface {
@@ -101,8 +153,40 @@ type Command struct {
 	SubCommands         []*Command
 	PositionalArguments []*PositionalArgument
 	Flags               []*Flag
+	// Other names the command can be entered with, shown next to the command in the completions
+	Aliases []string
+	// Groups of the command's flags that must be entered together or apart. Groups can include the
+	// persistent flags of parent commands
+	FlagGroups []FlagGroup
+	// Whether the command is left out of the completions, while still being accepted
+	Hidden bool
+	// The reason the command is deprecated, such as what to use instead. Deprecated commands are
+	// accepted with a warning on the SelectedCommandMsg, and shown struck through in the completions
+	Deprecated string
+	// Optional handler called when the command is entered and valid, instead of sending a SelectedCommandMsg
+	Run func(ctx Context) tea.Cmd
+}
+
+// FlagGroup is a set of flags, by short or long flag, that must be entered together or apart
+type FlagGroup struct {
+	Kind  FlagGroupKind
+	Flags []string
 }
 
+// FlagGroupKind is how many of the flags in a flag group can be entered
+type FlagGroupKind int
+
+const (
+	// At most one of the flags can be entered. Once one is entered the others aren't completed
+	MutuallyExclusive FlagGroupKind = iota
+	// If any of the flags are entered, all of them must be
+	RequiredTogether
+	// At least one of the flags must be entered
+	AtLeastOneOf
+	// Exactly one of the flags must be entered. Once one is entered the others aren't completed
+	ExactlyOneOf
+)
+
 func (c Command) getName() string {
 	return c.Command
 }
@@ -111,64 +195,232 @@ func (c Command) getDescription() string {
 	return c.Description
 }
 
+// hasName returns true if the name is the command or one of its aliases
+func (c Command) hasName(name string) bool {
+	return c.Command == name || slices.Contains(c.Aliases, name)
+}
+
+// hasPrefix returns true if the command or one of its aliases starts with the prefix
+func (c Command) hasPrefix(prefix string) bool {
+	if strings.HasPrefix(c.Command, prefix) {
+		return true
+	}
+	for _, alias := range c.Aliases {
+		if strings.HasPrefix(alias, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c Command) getAutocomplete() string {
 	return c.Command
 }
 
+// positionalAt returns the positional argument taking the value at the index, the variadic last
+// argument for any further values, or nil if the command takes no more values
+func (c Command) positionalAt(index int) *PositionalArgument {
+	if index < len(c.PositionalArguments) {
+		return c.PositionalArguments[index]
+	}
+	if len(c.PositionalArguments) == 0 {
+		return nil
+	}
+	last := c.PositionalArguments[len(c.PositionalArguments)-1]
+	if !last.Variadic || (last.MaxCount > 0 && index-len(c.PositionalArguments)+1 >= last.MaxCount) {
+		return nil
+	}
+	return last
+}
+
 type Argument interface {
-	getName() string
-	getDescription() string
-	getType() argumentType
+	Completion
+	getType() ArgumentType
+	getChoices() []Choice
+	getLayout() string
+	getConstraints() valueConstraints
+	getPathOptions() pathOptions
+	getCompleter() CompleterFunc
+	getAsyncCompleter() AsyncCompleterFunc
 }
 
-type argumentType string
+// valueConstraints are the optional limits on the value of a flag or positional argument
+type valueConstraints struct {
+	min       *float64
+	max       *float64
+	pattern   *regexp.Regexp
+	minLength int
+	maxLength int
+}
+
+// pathOptions are the optional limits on the path of a file or directory argument
+type pathOptions struct {
+	extensions []string
+	globs      []string
+	existence  PathExistence
+	writable   bool
+}
+
+// PathExistence is whether the path of a file or directory argument must already exist
+type PathExistence int
 
 const (
-	StringArgument  argumentType = "string"
-	IntArgument     argumentType = "int"
-	FloatArgument   argumentType = "float"
-	BoolArgument    argumentType = "bool"
-	FileArgument    argumentType = "file"
-	DirArgument     argumentType = "dir"
-	FileDirArgument argumentType = "filedir"
+	// The path must exist, the default
+	PathMustExist PathExistence = iota
+	// The path must not exist, such as for a new output file. Its parent directory must exist
+	PathMustNotExist
+	// The path may or may not exist. If it doesn't, its parent directory must exist
+	PathMayExist
 )
 
+// Choice is one of the allowed values of a ChoiceArgument
+type Choice struct {
+	Value       string
+	Description string
+}
+
 type PositionalArgument struct {
 	Name        string
 	Description string
-	Type        argumentType
+	Type        ArgumentType
 	Required    bool
+	// Whether the argument takes every remaining value, i.e. `rm file...`. Only the last positional
+	// argument can be variadic
+	Variadic bool
+	// The minimum and maximum number of values of a variadic argument, 0 for no limit. A required
+	// variadic argument takes at least one value
+	MinCount int
+	MaxCount int
+	// The allowed values when the type is ChoiceArgument
+	Choices []Choice
+	// The time.Parse layout when the type is TimeArgument, RFC 3339 if empty
+	Layout string
+	// The minimum and maximum values when the type is IntArgument or FloatArgument, nil for no limit
+	Min *float64
+	Max *float64
+	// Optional regular expression the whole value must match, as if anchored with ^ and $
+	Pattern *regexp.Regexp
+	// The minimum and maximum number of characters in the value, 0 for no limit
+	MinLength int
+	MaxLength int
+	// The allowed file extensions when the type is FileArgument or FileDirArgument, i.e. ".csv"
+	Extensions []string
+	// The glob patterns allowed file names match when the type is FileArgument or FileDirArgument, i.e. "*.csv"
+	Globs []string
+	// Whether the path must exist when the type is a file or directory type
+	Existence PathExistence
+	// Whether the path must be writable, or its parent directory if it doesn't exist
+	Writable bool
+	// Optional function returning the values to suggest for the argument
+	Completer CompleterFunc
+	// Optional function returning the values to suggest for the argument, run in the background
+	AsyncCompleter AsyncCompleterFunc
 }
 
 func (a PositionalArgument) getName() string {
 	return a.Name
 }
 
+// minValues returns the number of values that must be entered for the argument
+func (a PositionalArgument) minValues() int {
+	required := 0
+	if a.Required {
+		required = 1
+	}
+	if a.Variadic {
+		return max(a.MinCount, required)
+	}
+	return required
+}
+
 func (a PositionalArgument) getDescription() string {
 	isRequired := "required"
 	if !a.Required {
 		isRequired = "optional"
 	}
-	if a.Type == BoolArgument {
+	label := valueLabel(a.Type, a.getConstraints())
+	if label == "" {
 		return fmt.Sprintf("%s [%s]", a.Description, isRequired)
 	}
-	return fmt.Sprintf("%s [%s] [%s]", a.Description, a.Type, isRequired)
+	return fmt.Sprintf("%s [%s] [%s]", a.Description, label, isRequired)
 }
 
 func (a PositionalArgument) getAutocomplete() string {
 	return ""
 }
 
-func (a PositionalArgument) getType() argumentType {
+func (a PositionalArgument) getType() ArgumentType {
 	return a.Type
 }
 
+func (a PositionalArgument) getChoices() []Choice {
+	return a.Choices
+}
+
+func (a PositionalArgument) getLayout() string {
+	return a.Layout
+}
+
+func (a PositionalArgument) getConstraints() valueConstraints {
+	return valueConstraints{min: a.Min, max: a.Max, pattern: a.Pattern, minLength: a.MinLength, maxLength: a.MaxLength}
+}
+
+func (a PositionalArgument) getPathOptions() pathOptions {
+	return pathOptions{extensions: a.Extensions, globs: a.Globs, existence: a.Existence, writable: a.Writable}
+}
+
+func (a PositionalArgument) getCompleter() CompleterFunc {
+	return a.Completer
+}
+
+func (a PositionalArgument) getAsyncCompleter() AsyncCompleterFunc {
+	return a.AsyncCompleter
+}
+
 type Flag struct {
 	ShortFlag   string
 	LongFlag    string
 	Description string
-	Type        argumentType
+	Type        ArgumentType
 	Persistent  bool
+	// Whether the flag must be entered. Required flags are listed first in the completions
+	Required bool
+	// Other flags, by short or long flag, that must be entered when this flag is
+	Requires []string
+	// Whether the flag can be entered more than once, collecting each of its values. Repeatable
+	// flags are still completed once entered, while other flags can only be entered once
+	Repeatable bool
+	// Whether a BoolArgument flag counts the times it's entered, i.e. 3 for `-vvv`. Count flags are repeatable
+	Count bool
+	// The allowed values when the type is ChoiceArgument
+	Choices []Choice
+	// The time.Parse layout when the type is TimeArgument, RFC 3339 if empty
+	Layout string
+	// The minimum and maximum values when the type is IntArgument or FloatArgument, nil for no limit
+	Min *float64
+	Max *float64
+	// Optional regular expression the whole value must match, as if anchored with ^ and $
+	Pattern *regexp.Regexp
+	// The minimum and maximum number of characters in the value, 0 for no limit
+	MinLength int
+	MaxLength int
+	// The allowed file extensions when the type is FileArgument or FileDirArgument, i.e. ".csv"
+	Extensions []string
+	// The glob patterns allowed file names match when the type is FileArgument or FileDirArgument, i.e. "*.csv"
+	Globs []string
+	// Whether the path must exist when the type is a file or directory type
+	Existence PathExistence
+	// Whether the path must be writable, or its parent directory if it doesn't exist
+	Writable bool
+	// Optional function returning the values to suggest for the flag
+	Completer CompleterFunc
+	// Optional function returning the values to suggest for the flag, run in the background
+	AsyncCompleter AsyncCompleterFunc
+	// Whether the flag is left out of the completions, while still being accepted
+	Hidden bool
+	// The reason the flag is deprecated, such as what to use instead. Deprecated flags are
+	// accepted with a warning on the SelectedCommandMsg, and shown struck through in the completions
+	Deprecated string
 }
 
 func (a Flag) getName() string {
@@ -182,10 +434,24 @@ func (a Flag) getName() string {
 }
 
 func (a Flag) getDescription() string {
-	if a.Type == BoolArgument {
-		return a.Description
+	description := a.Description
+	if label := valueLabel(a.Type, a.getConstraints()); label != "" {
+		description = fmt.Sprintf("%s [%s]", description, label)
+	}
+	return description
+}
+
+// repeatable returns true if the flag can be entered more than once
+func (a Flag) repeatable() bool {
+	return a.Repeatable || a.Count
+}
+
+// displayName returns the long flag if there is one, or the short flag otherwise
+func (a Flag) displayName() string {
+	if a.LongFlag != "" {
+		return a.LongFlag
 	}
-	return fmt.Sprintf("%s [%s]", a.Description, a.Type)
+	return a.ShortFlag
 }
 
 func (a Flag) getAutocomplete() string {
@@ -195,10 +461,65 @@ func (a Flag) getAutocomplete() string {
 	return a.LongFlag
 }
 
-func (a Flag) getType() argumentType {
+func (a Flag) getType() ArgumentType {
 	return a.Type
 }
 
+func (a Flag) getChoices() []Choice {
+	return a.Choices
+}
+
+func (a Flag) getLayout() string {
+	return a.Layout
+}
+
+func (a Flag) getConstraints() valueConstraints {
+	return valueConstraints{min: a.Min, max: a.Max, pattern: a.Pattern, minLength: a.MinLength, maxLength: a.MaxLength}
+}
+
+func (a Flag) getPathOptions() pathOptions {
+	return pathOptions{extensions: a.Extensions, globs: a.Globs, existence: a.Existence, writable: a.Writable}
+}
+
+func (a Flag) getCompleter() CompleterFunc {
+	return a.Completer
+}
+
+func (a Flag) getAsyncCompleter() AsyncCompleterFunc {
+	return a.AsyncCompleter
+}
+
+// CompleterFunc returns the values to suggest for a flag or positional argument
+//
+// partial is the value typed so far, and parsed holds the command entered before the value.
+// The function should only return the suggestions that match partial.
+type CompleterFunc func(partial string, parsed *ParsedCommand) []Suggestion
+
+// AsyncCompleterFunc returns the values to suggest for a flag or positional argument, for
+// suggestions that are slow to find
+//
+// The function is run in the background so typing isn't blocked, and ctx is cancelled once
+// the input changes. The function should only return the suggestions that match partial.
+type AsyncCompleterFunc func(ctx context.Context, partial string, parsed *ParsedCommand) ([]Suggestion, error)
+
+// Suggestion is a value suggested for a flag or positional argument by a completer
+type Suggestion struct {
+	Value       string
+	Description string
+}
+
+func (s Suggestion) getName() string {
+	return s.Value
+}
+
+func (s Suggestion) getDescription() string {
+	return s.Description
+}
+
+func (s Suggestion) getAutocomplete() string {
+	return quoteValue(s.Value)
+}
+
 // MARK: Public Functions
 
 // New creates a new model with the given commands
@@ -228,6 +549,8 @@ func New(commands []*Command, width int) (Model, error) {
 	progress := progress.New(progress.WithDefaultGradient())
 	progress.ShowPercentage = false
 
+	spinner := spinner.New(spinner.WithSpinner(spinner.MiniDot), spinner.WithStyle(lg.Foreground(pink)))
+
 	return Model{
 		input:               input,
 		Commands:            commands,
@@ -240,11 +563,20 @@ func New(commands []*Command, width int) (Model, error) {
 		CompletionsOffset:   0,
 		ValidCommandStyle:   lg.Foreground(green),
 		InvalidCommandStyle: lg.Foreground(textColor),
+		PathPrefixStyle:     lg.Foreground(textColor).Underline(true),
+		PathExistsStyle:     lg.Foreground(green),
+		PathInvalidStyle:    lg.Foreground(red),
 		scrollbarProgress:   progress,
+		spinner:             spinner,
 		ShowBorderScroll:    false,
 		ShowScrollbar:       false,
 		CompletionsPosition: PositionBelow,
 		CompletionRows:      5,
+		CompletionCacheTTL:  30 * time.Second,
+		CompletionCacheSize: 100,
+		FilePickerKey:       key.NewBinding(key.WithKeys("ctrl+o")),
+		FilePickerRows:      10,
+		completionCache:     newCompletionCache(),
 	}, nil
 }
 
@@ -261,9 +593,19 @@ func (m *Model) SetPlaceholder(placeholder string) {
 }
 
 func (c *Command) Validate() error {
+	return c.validate(nil)
+}
+
+// validate validates the command and its subcommands, given the persistent flags of its parents
+func (c *Command) validate(persistent []*Flag) error {
 	if c.Command == "" {
 		return fmt.Errorf("commands must have a command name")
 	}
+	for _, alias := range c.Aliases {
+		if alias == "" || strings.ContainsFunc(alias, unicode.IsSpace) {
+			return fmt.Errorf("command aliases must be a single word")
+		}
+	}
 
 	for _, flag := range c.Flags {
 		if err := flag.Validate(); err != nil {
@@ -271,14 +613,41 @@ func (c *Command) Validate() error {
 		}
 	}
 
-	for _, arg := range c.PositionalArguments {
+	for i, arg := range c.PositionalArguments {
 		if err := arg.Validate(); err != nil {
 			return err
 		}
+		if arg.Variadic && i != len(c.PositionalArguments)-1 {
+			return fmt.Errorf("only the last positional argument can be variadic")
+		}
 	}
 
+	available := append(append([]*Flag{}, c.Flags...), persistent...)
+	for _, flag := range c.Flags {
+		for _, name := range flag.Requires {
+			if _, err := findFlag(available, name); err != nil {
+				return fmt.Errorf("unknown flag in requires of flag: %s", flag.displayName())
+			}
+		}
+	}
+	for _, group := range c.FlagGroups {
+		if len(group.Flags) < 2 {
+			return fmt.Errorf("flag groups must have at least two flags")
+		}
+		for _, name := range group.Flags {
+			if _, err := findFlag(available, name); err != nil {
+				return fmt.Errorf("unknown flag in flag group: %s", name)
+			}
+		}
+	}
+
+	for _, flag := range c.Flags {
+		if flag.Persistent {
+			persistent = append(persistent, flag)
+		}
+	}
 	for _, subCmd := range c.SubCommands {
-		if err := subCmd.Validate(); err != nil {
+		if err := subCmd.validate(slices.Clip(persistent)); err != nil {
 			return err
 		}
 	}
@@ -293,6 +662,24 @@ func (p *PositionalArgument) Validate() error {
 	if p.Type == "" {
 		return fmt.Errorf("positional arguments must have a type")
 	}
+	if err := validateArgumentType(p.Type); err != nil {
+		return err
+	}
+	if err := validateConstraintDefinition(p.Type, p.getConstraints()); err != nil {
+		return err
+	}
+	if err := validatePathOptionsDefinition(p.Type, p.getPathOptions()); err != nil {
+		return err
+	}
+	if p.Type == ChoiceArgument && len(p.Choices) == 0 {
+		return fmt.Errorf("choice arguments must have at least one choice")
+	}
+	if !p.Variadic && (p.MinCount != 0 || p.MaxCount != 0) {
+		return fmt.Errorf("min and max counts are only for variadic arguments")
+	}
+	if p.MinCount < 0 || p.MaxCount < 0 || (p.MaxCount > 0 && p.MaxCount < p.MinCount) {
+		return fmt.Errorf("invalid min and max counts for argument: %s", p.Name)
+	}
 	return nil
 }
 
@@ -327,5 +714,20 @@ func (f *Flag) Validate() error {
 	if f.Type == "" {
 		return fmt.Errorf("flags must have a type")
 	}
+	if err := validateArgumentType(f.Type); err != nil {
+		return err
+	}
+	if err := validateConstraintDefinition(f.Type, f.getConstraints()); err != nil {
+		return err
+	}
+	if err := validatePathOptionsDefinition(f.Type, f.getPathOptions()); err != nil {
+		return err
+	}
+	if f.Type == ChoiceArgument && len(f.Choices) == 0 {
+		return fmt.Errorf("choice arguments must have at least one choice")
+	}
+	if f.Count && f.Type != BoolArgument {
+		return fmt.Errorf("count flags must be bool flags")
+	}
 	return nil
 }
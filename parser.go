@@ -0,0 +1,338 @@
+package bubblecomplete
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"slices"
+	"strings"
+)
+
+// MARK: Types and Vars
+
+// parsedInput is the parse tree of the input, shared by completion and validation
+type parsedInput struct {
+	input        string
+	tokens       []Token
+	rootCommands []*Command
+	// The resolved commands, from the root command to the deepest subcommand
+	commands []*Command
+	// The flags entered, in the order they were entered
+	flags []*parsedFlag
+	// The positional argument values entered, in the order they were entered
+	positionals []*parsedPositional
+	// The token under the cursor and the state of the parse at that point
+	cursor cursorContext
+	// The first structural error found, such as an unknown flag or an unexpected argument
+	err error
+	// The cache for completer results, nil to not cache them
+	cache *completionCache
+	// The filesystem and working directory paths are resolved against
+	fsys       fs.FS
+	workingDir string
+}
+
+type parsedFlag struct {
+	flag *Flag
+	// The flag as it was typed, i.e. `-m` when entered as part of `-am`
+	name string
+	// The index of the token containing the flag
+	token int
+	// The index of the token containing the value, or -1 if there's no separate value token
+	valueToken int
+	// The value of the flag, if one was given
+	value    string
+	hasValue bool
+}
+
+type parsedPositional struct {
+	arg *PositionalArgument
+	// The index of the token containing the value
+	token int
+}
+
+type cursorContext struct {
+	// The index of the token under the cursor, len(tokens) when starting a new token
+	index int
+	// The token under the cursor, empty when starting a new token
+	token Token
+	// The deepest command resolved before the cursor
+	command *Command
+	// The number of commands resolved before the cursor
+	depth int
+	// The flag waiting for a value at the cursor, if any
+	pendingFlag *Flag
+	// The number of flags and positional values entered before the cursor
+	flagCount       int
+	positionalCount int
+}
+
+// MARK: Private Functions
+
+// parseInput parses the input against the available commands
+//
+// The cursor is assumed to be at the end of the input. Parsing carries on past errors where
+// possible, so the cursor context is still available for completions.
+func parseInput(input string, commands []*Command) *parsedInput {
+	p := &parsedInput{input: input, tokens: Tokenize(input), rootCommands: commands}
+
+	p.cursor.index = len(p.tokens)
+	if len(p.tokens) > 0 && p.tokens[len(p.tokens)-1].ByteEnd == len(input) {
+		p.cursor.index--
+		p.cursor.token = p.tokens[p.cursor.index]
+	}
+
+	var pending *parsedFlag
+	for i, token := range p.tokens {
+		if i == p.cursor.index {
+			p.markCursor(pending)
+		}
+
+		// A flag waiting for a value takes this token, unless it's another flag
+		if pending != nil {
+			if !isFlagToken(token) {
+				pending.valueToken = i
+				pending.value = token.Value
+				pending.hasValue = true
+				pending = nil
+				continue
+			}
+			p.fail(fmt.Errorf("missing value for flag '%s'", pending.name))
+			pending = nil
+		}
+
+		if len(p.commands) == 0 {
+			if isFlagToken(token) {
+				p.fail(errors.New("invalid flag: " + token.Raw))
+				break
+			}
+			cmd, err := findCommand(commands, token.Value)
+			if err != nil {
+				p.fail(errors.New("invalid command: " + token.Raw))
+				break
+			}
+			p.commands = append(p.commands, cmd)
+			continue
+		}
+
+		if isFlagToken(token) {
+			pending = p.parseFlag(i, token)
+			continue
+		}
+
+		// Subcommands can only be entered before any positional arguments
+		cmd := p.command()
+		if len(p.positionals) == 0 {
+			if subCmd, err := findCommand(cmd.SubCommands, token.Value); err == nil {
+				p.commands = append(p.commands, subCmd)
+				continue
+			}
+		}
+
+		if arg := cmd.positionalAt(len(p.positionals)); arg != nil {
+			p.positionals = append(p.positionals, &parsedPositional{arg: arg, token: i})
+			continue
+		}
+
+		if n := len(cmd.PositionalArguments); n > 0 && cmd.PositionalArguments[n-1].Variadic {
+			last := cmd.PositionalArguments[n-1]
+			p.fail(fmt.Errorf("too many values for argument: %s (at most %d)", last.Name, last.MaxCount))
+			continue
+		}
+		p.fail(errors.New("unexpected argument: " + token.Raw))
+	}
+
+	if p.cursor.index == len(p.tokens) {
+		p.markCursor(pending)
+	}
+	if pending != nil {
+		p.fail(fmt.Errorf("missing value for flag '%s'", pending.name))
+	}
+
+	return p
+}
+
+// parseFlag parses a flag token, returning the flag if it's still waiting for a value
+func (p *parsedInput) parseFlag(index int, token Token) *parsedFlag {
+	if strings.HasPrefix(token.Raw, "--") {
+		name, value, hasValue := strings.Cut(token.Value, "=")
+		flag, err := findFlag(p.availableFlags(len(p.commands)), name)
+		if err != nil {
+			p.fail(fmt.Errorf("flag '%s' not found", name))
+			return nil
+		}
+
+		parsed := &parsedFlag{flag: flag, name: name, token: index, valueToken: -1, value: value, hasValue: hasValue}
+		p.flags = append(p.flags, parsed)
+		if flag.Type != BoolArgument && !hasValue {
+			return parsed
+		}
+		return nil
+	}
+
+	combinedFlags := []rune(token.Value[1:])
+	if len(combinedFlags) == 0 {
+		p.fail(errors.New("invalid argument: " + token.Raw))
+		return nil
+	}
+
+	for j, char := range combinedFlags {
+		name := "-" + string(char)
+		flag, err := findFlag(p.availableFlags(len(p.commands)), name)
+		if err != nil {
+			p.fail(fmt.Errorf("flag '%s' not found", name))
+			continue
+		}
+
+		parsed := &parsedFlag{flag: flag, name: name, token: index, valueToken: -1}
+		p.flags = append(p.flags, parsed)
+		if flag.Type == BoolArgument {
+			continue
+		}
+		if j != len(combinedFlags)-1 {
+			p.fail(fmt.Errorf("flag '%s' must be the last in a combined group", name))
+			continue
+		}
+		return parsed
+	}
+	return nil
+}
+
+// markCursor records the state of the parse at the cursor
+func (p *parsedInput) markCursor(pending *parsedFlag) {
+	p.cursor.command = p.command()
+	p.cursor.depth = len(p.commands)
+	p.cursor.flagCount = len(p.flags)
+	p.cursor.positionalCount = len(p.positionals)
+	if pending != nil {
+		p.cursor.pendingFlag = pending.flag
+	}
+}
+
+// cursorValue returns the value typed so far at the cursor, excluding the flag of a long flag with an equals sign
+func (p *parsedInput) cursorValue() string {
+	if _, value, found := p.cursorInlineFlag(); found {
+		return value
+	}
+	return p.cursor.token.Value
+}
+
+// cursorMaybeSubCommand returns true if the token at the cursor could still be a subcommand
+func (p *parsedInput) cursorMaybeSubCommand() bool {
+	cursor := p.cursor
+	if cursor.command == nil || cursor.positionalCount > 0 {
+		return false
+	}
+	for _, cmd := range cursor.command.SubCommands {
+		if (!cmd.Hidden && cmd.hasPrefix(cursor.token.Value)) || cmd.hasName(cursor.token.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// cursorInlineFlag returns the flag name and value if a long flag with an equals sign is at the cursor
+func (p *parsedInput) cursorInlineFlag() (string, string, bool) {
+	if !strings.HasPrefix(p.cursor.token.Raw, "--") {
+		return "", "", false
+	}
+	return strings.Cut(p.cursor.token.Value, "=")
+}
+
+// replaceStart returns the byte offset in the input that a completion for the cursor replaces from
+func (p *parsedInput) replaceStart() int {
+	if p.cursor.index == len(p.tokens) {
+		return len(p.input)
+	}
+	if _, _, found := p.cursorInlineFlag(); found {
+		return p.cursor.token.ByteStart + strings.Index(p.cursor.token.Raw, "=") + 1
+	}
+	return p.cursor.token.ByteStart
+}
+
+// fail records the error if it's the first one found
+func (p *parsedInput) fail(err error) {
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+// command returns the deepest command resolved, or nil if there isn't one
+func (p *parsedInput) command() *Command {
+	if len(p.commands) == 0 {
+		return nil
+	}
+	return p.commands[len(p.commands)-1]
+}
+
+// availableFlags returns the flags of the command at the given depth and the persistent flags of its parents
+func (p *parsedInput) availableFlags(depth int) []*Flag {
+	if depth == 0 {
+		return nil
+	}
+
+	flags := append([]*Flag{}, p.commands[depth-1].Flags...)
+	for _, parent := range p.commands[:depth-1] {
+		for _, flag := range parent.Flags {
+			if flag.Persistent {
+				flags = append(flags, flag)
+			}
+		}
+	}
+	return flags
+}
+
+// enteredFlag returns true if the flag was entered before the given number of parsed flags
+func (p *parsedInput) enteredFlag(flag *Flag, count int) bool {
+	return p.findParsedFlag(flag, count) != nil
+}
+
+// findParsedFlag returns the first entry of the flag before the given number of parsed flags, or nil
+func (p *parsedInput) findParsedFlag(flag *Flag, count int) *parsedFlag {
+	for _, parsed := range p.flags[:count] {
+		if parsed.flag == flag {
+			return parsed
+		}
+	}
+	return nil
+}
+
+// groupFlags returns the flags of the flag group, from the flags available to the command at the given depth
+func (p *parsedInput) groupFlags(depth int, group FlagGroup) []*Flag {
+	available := p.availableFlags(depth)
+	flags := []*Flag{}
+	for _, name := range group.Flags {
+		if flag, err := findFlag(available, name); err == nil {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// excludedFlag returns true if another flag in a mutually exclusive group with the flag was
+// entered before the given number of parsed flags
+func (p *parsedInput) excludedFlag(flag *Flag, depth int, count int) bool {
+	if depth == 0 {
+		return false
+	}
+	for _, group := range p.commands[depth-1].FlagGroups {
+		if group.Kind != MutuallyExclusive && group.Kind != ExactlyOneOf {
+			continue
+		}
+		flags := p.groupFlags(depth, group)
+		if !slices.Contains(flags, flag) {
+			continue
+		}
+		for _, other := range flags {
+			if other != flag && p.enteredFlag(other, count) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isFlagToken returns true if the token starts with an unquoted dash
+func isFlagToken(token Token) bool {
+	return strings.HasPrefix(token.Raw, "-")
+}
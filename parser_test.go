@@ -0,0 +1,82 @@
+package bubblecomplete
+
+import "testing"
+
+func TestParseInputCommands(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"git", []string{"git"}},
+		{"git stash pop", []string{"git", "stash", "pop"}},
+		{"git --help stash --help pop", []string{"git", "stash", "pop"}},
+		{"git push stash", []string{"git", "push"}},
+		{"git commit -m stash", []string{"git", "commit"}},
+	}
+
+	for _, c := range cases {
+		p := parseInput(c.input, testCommands())
+		names := []string{}
+		for _, cmd := range p.commands {
+			names = append(names, cmd.Command)
+		}
+		if len(names) != len(c.expected) {
+			t.Errorf("parseInput(%q) commands == %q, expected %q", c.input, names, c.expected)
+			continue
+		}
+		for i := range names {
+			if names[i] != c.expected[i] {
+				t.Errorf("parseInput(%q) commands == %q, expected %q", c.input, names, c.expected)
+				break
+			}
+		}
+	}
+}
+
+func TestParseInputFlags(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected map[string]string
+	}{
+		{"git commit -m \"Added flag -a\" --amend", map[string]string{"-m": "Added flag -a", "--amend": ""}},
+		{"git commit --message=value", map[string]string{"--message": "value"}},
+		{"git commit -am 'hello world'", map[string]string{"-a": "", "-m": "hello world"}},
+		{"git commit '--amend'", map[string]string{}},
+		{"git --help commit --all", map[string]string{"--help": "", "--all": ""}},
+	}
+
+	for _, c := range cases {
+		p := parseInput(c.input, testCommands())
+		if len(p.flags) != len(c.expected) {
+			t.Errorf("parseInput(%q) parsed %d flags, expected %d", c.input, len(p.flags), len(c.expected))
+			continue
+		}
+		for _, flag := range p.flags {
+			value, ok := c.expected[flag.name]
+			if !ok {
+				t.Errorf("parseInput(%q) parsed unexpected flag %q", c.input, flag.name)
+				continue
+			}
+			if flag.value != value {
+				t.Errorf("parseInput(%q) flag %q == %q, expected %q", c.input, flag.name, flag.value, value)
+			}
+		}
+	}
+}
+
+func TestParseInputCursor(t *testing.T) {
+	p := parseInput("git commit -m ", testCommands())
+	if p.cursor.index != 3 || p.cursor.command.Command != "commit" || p.cursor.pendingFlag == nil {
+		t.Errorf("unexpected cursor %+v", p.cursor)
+	}
+
+	p = parseInput("git commit -m hel", testCommands())
+	if p.cursor.index != 3 || p.cursor.token.Value != "hel" || p.cursor.pendingFlag == nil {
+		t.Errorf("unexpected cursor %+v", p.cursor)
+	}
+
+	p = parseInput("git stash", testCommands())
+	if p.cursor.index != 1 || p.cursor.command.Command != "git" || p.cursor.depth != 1 {
+		t.Errorf("unexpected cursor %+v", p.cursor)
+	}
+}
@@ -0,0 +1,85 @@
+package bubblecomplete
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MARK: Private Functions
+
+// statPath returns the file info of the path, resolved against the filesystem and working directory of the context
+func statPath(ctx ValueContext, name string) (fs.FileInfo, error) {
+	if ctx.FS == nil {
+		return os.Stat(osPath(ctx, name))
+	}
+	fsName, err := fsPath(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.Stat(ctx.FS, fsName)
+}
+
+// readDirPath returns the entries of the directory, resolved against the filesystem and working directory of the context
+func readDirPath(ctx ValueContext, name string) ([]fs.DirEntry, error) {
+	if ctx.FS == nil {
+		return os.ReadDir(osPath(ctx, name))
+	}
+	fsName, err := fsPath(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.ReadDir(ctx.FS, fsName)
+}
+
+// isWritable returns true if the path can be written to
+//
+// On Unix the access of the current user is checked. An fs.FS has no users, so a path in one, like
+// on the OS filesystem elsewhere, is taken to be writable if any of its write permission bits are
+// set. This is a heuristic, as the bits may not apply to whoever ends up writing to it.
+func isWritable(ctx ValueContext, name string, info fs.FileInfo) bool {
+	if ctx.FS == nil {
+		return canWrite(osPath(ctx, name))
+	}
+	return info.Mode().Perm()&0o222 != 0
+}
+
+// parentPath returns the parent directory of the path
+func parentPath(ctx ValueContext, name string) string {
+	if ctx.FS == nil {
+		return filepath.Dir(expandHome(name))
+	}
+	return path.Dir(name)
+}
+
+// osPath returns the path on the OS filesystem, with `~` expanded and relative paths joined to the working directory
+func osPath(ctx ValueContext, name string) string {
+	name = expandHome(name)
+	if name == "" {
+		name = "."
+	}
+	if ctx.WorkingDir != "" && !filepath.IsAbs(name) {
+		name = filepath.Join(ctx.WorkingDir, name)
+	}
+	return name
+}
+
+// fsPath returns the name of the path in the fs.FS of the context
+//
+// Absolute paths are resolved from the root of the filesystem and relative paths from the working
+// directory. Returns an error if the path is outside of the filesystem.
+func fsPath(ctx ValueContext, name string) (string, error) {
+	if !strings.HasPrefix(name, "/") {
+		name = path.Join(ctx.WorkingDir, name)
+	}
+	name = strings.TrimPrefix(path.Clean(name), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return name, nil
+}
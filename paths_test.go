@@ -0,0 +1,242 @@
+package bubblecomplete
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func testFS() fstest.MapFS {
+	return fstest.MapFS{
+		"home/notes.txt":        {},
+		"home/docs/report.pdf":  {},
+		"home/.config/app.json": {},
+		"etc/hosts":             {},
+	}
+}
+
+func testPathCommands() []*Command {
+	return []*Command{
+		{
+			Command: "cp",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileArgument, Required: true},
+				{Name: "destination", Type: DirArgument},
+			},
+		},
+	}
+}
+
+func parseFSInput(input string) *parsedInput {
+	p := parseInput(input, testPathCommands())
+	p.fsys = testFS()
+	p.workingDir = "home"
+	return p
+}
+
+func TestValidatePathsWithFS(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"cp notes.txt docs", ""},
+		{"cp /etc/hosts .", ""},
+		{"cp docs/report.pdf ../etc", ""},
+		{"cp missing.txt", "file does not exist for argument: file"},
+		{"cp docs", "file path is a directory: file"},
+		{"cp notes.txt /etc/hosts", "directory path is a file: destination"},
+		{"cp ../../outside.txt", "file does not exist for argument: file"},
+	}
+
+	for _, c := range cases {
+		err := validateCommandInput(parseFSInput(c.input))
+		result := ""
+		if err != nil {
+			result = err.Error()
+		}
+		if result != c.expected {
+			t.Errorf("validateCommandInput(%q) == %q, expected %q", c.input, result, c.expected)
+		}
+	}
+}
+
+func TestCompletePathsWithFS(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"cp ", []string{"docs/", "notes.txt"}},
+		{"cp .", []string{".config/"}},
+		{"cp docs/", []string{"docs/report.pdf"}},
+		{"cp /", []string{"/etc/", "/home/"}},
+		{"cp notes.txt ", []string{"docs/"}},
+		{"cp ~", []string{"file"}},
+	}
+
+	for _, c := range cases {
+		p := parseFSInput(c.input)
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
+	}
+}
+
+func TestCompleteVariadicPaths(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"rm notes.txt ", []string{"docs/", "notes.txt"}},
+		{"rm notes.txt docs/report.pdf d", []string{"docs/"}},
+	}
+
+	for _, c := range cases {
+		p := parseInput(c.input, testVariadicCommands())
+		p.fsys = testFS()
+		p.workingDir = "home"
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
+	}
+
+	p := parseInput("rm notes.txt docs/report.pdf", testVariadicCommands())
+	p.fsys = testFS()
+	p.workingDir = "home"
+	if err := validateCommandInput(p); err != nil {
+		t.Errorf("validateCommandInput() == %v, expected nil", err)
+	}
+}
+
+func TestModelFS(t *testing.T) {
+	m, err := New(testPathCommands(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.FS = testFS()
+	m.WorkingDir = "home"
+
+	if err := validateCommandInput(m.parseInput("cp notes.txt")); err != nil {
+		t.Errorf("expected notes.txt to exist in the working directory, got %v", err)
+	}
+}
+
+func TestValidatePathOptions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data":             {Mode: fs.ModeDir | 0o755},
+		"data/sales.csv":   {Mode: 0o644},
+		"data/notes.txt":   {Mode: 0o644},
+		"data/locked.csv":  {Mode: 0o444},
+		"readonly/old.csv": {Mode: 0o644},
+		"readonly":         {Mode: fs.ModeDir | 0o555},
+	}
+	commands := []*Command{
+		{
+			Command: "convert",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "input", Type: FileArgument, Required: true, Extensions: []string{".csv"}, Globs: []string{"*.tsv"}},
+			},
+			Flags: []*Flag{
+				{LongFlag: "--out", Type: FileArgument, Existence: PathMustNotExist, Writable: true},
+				{LongFlag: "--append", Type: FileArgument, Existence: PathMayExist, Writable: true},
+			},
+		},
+	}
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"convert data/sales.csv --out data/report.json", ""},
+		{"convert data/notes.txt", "invalid file name 'notes.txt' for argument: input (expected .csv, *.tsv)"},
+		{"convert data/sales.csv --out data/notes.txt", "file already exists for argument: --out"},
+		{"convert data/sales.csv --out missing/report.json", "parent directory does not exist for argument: --out"},
+		{"convert data/sales.csv --out readonly/report.json", "parent directory is not writable for argument: --out"},
+		{"convert data/sales.csv --append data/sales.csv", ""},
+		{"convert data/sales.csv --append data/new.csv", ""},
+		{"convert data/sales.csv --append data/locked.csv", "file is not writable for argument: --append"},
+	}
+
+	for _, c := range cases {
+		p := parseInput(c.input, commands)
+		p.fsys = fsys
+		err := validateCommandInput(p)
+		result := ""
+		if err != nil {
+			result = err.Error()
+		}
+		if result != c.expected {
+			t.Errorf("validateCommandInput(%q) == %q, expected %q", c.input, result, c.expected)
+		}
+	}
+
+	completionCases := []struct {
+		input    string
+		expected []string
+	}{
+		{"convert data/", []string{"data/locked.csv", "data/sales.csv"}},
+		{"convert data/sales.csv --out data/", []string{"--out"}},
+		{"convert data/sales.csv --append data/", []string{"data/notes.txt", "data/sales.csv"}},
+	}
+	for _, c := range completionCases {
+		p := parseInput(c.input, commands)
+		p.fsys = fsys
+		completions := getCompletions(p)
+		sortCompletions(&completions, p)
+		if names := completionNames(completions); !slices.Equal(names, c.expected) {
+			t.Errorf("getCompletions(%q) == %v, expected %v", c.input, names, c.expected)
+		}
+	}
+
+	invalid := []*Flag{
+		{LongFlag: "--dir", Type: DirArgument, Extensions: []string{".csv"}},
+		{LongFlag: "--name", Type: StringArgument, Existence: PathMustNotExist},
+		{LongFlag: "--in", Type: FileArgument, Globs: []string{"[a-"}},
+	}
+	for _, flag := range invalid {
+		if err := flag.Validate(); err == nil {
+			t.Errorf("Validate() expected an error for the path options of %+v", flag)
+		}
+	}
+}
+
+func TestIsWritableOS(t *testing.T) {
+	dir := t.TempDir()
+	writable := filepath.Join(dir, "writable.txt")
+	locked := filepath.Join(dir, "locked.txt")
+	if err := os.WriteFile(writable, []byte("test"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(locked, []byte("test"), 0o444); err != nil {
+		t.Fatal(err)
+	}
+
+	// The access of the current user is checked rather than the permission bits, so root can
+	// write to files without any write bits set
+	root := os.Geteuid() == 0
+	cases := []struct {
+		path     string
+		expected bool
+	}{
+		{dir, true},
+		{writable, true},
+		{locked, root},
+	}
+
+	ctx := ValueContext{}
+	for _, c := range cases {
+		info, err := os.Stat(c.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result := isWritable(ctx, c.path, info); result != c.expected {
+			t.Errorf("isWritable(%q) == %t, expected %t", c.path, result, c.expected)
+		}
+	}
+}
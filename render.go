@@ -2,6 +2,7 @@ package bubblecomplete
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -9,11 +10,33 @@ import (
 var scrollbarPercent float64
 var minCompletionsSize = 60
 
+// pathHighlight is the range of runes in the input holding a path, and whether the path exists
+type pathHighlight struct {
+	start int
+	end   int
+	state pathState
+}
+
+type pathState int
+
+const (
+	// The path is the start of an existing path
+	pathPrefix pathState = iota
+	// The path exists
+	pathExists
+	// The path isn't valid for the argument
+	pathInvalid
+	// The path doesn't exist, but is valid for the argument
+	pathNew
+)
+
 // MARK: Public Functions
 
 func (m Model) View() string {
 	var output string
-	if m.historyIndex != -1 {
+	if m.pickingPath {
+		output = m.filePickerRender()
+	} else if m.historyIndex != -1 {
 		output = m.input.View()
 	} else {
 		output = m.showCompletionsRender()
@@ -39,18 +62,26 @@ func (m Model) showCompletionsRender() string {
 	// Get each completion text
 	if len(m.completions) > 0 && (len(m.input.Value()) > 0 || m.showAll) {
 		for _, comp := range m.completions {
-			name := comp.getName()
-			description := comp.getDescription()
+			name := completionTitle(comp)
+			description := completionDescription(m.parsed, comp)
 
 			completionTitles = append(completionTitles, name)
 			completionDescriptions = append(completionDescriptions, description)
+		}
+	}
+
+	// Show a loading row while background completions are being found
+	if m.completionsPending && len(m.input.Value()) > 0 {
+		completionTitles = append(completionTitles, m.spinner.View())
+		completionDescriptions = append(completionDescriptions, "Loading completions...")
+	}
 
-			if len(name) > maxTitleLength {
-				maxTitleLength = len(name)
-			}
-			if len(description) > maxDescriptionLength {
-				maxDescriptionLength = len(description)
-			}
+	for i := range completionTitles {
+		if lipgloss.Width(completionTitles[i]) > maxTitleLength {
+			maxTitleLength = lipgloss.Width(completionTitles[i])
+		}
+		if lipgloss.Width(completionDescriptions[i]) > maxDescriptionLength {
+			maxDescriptionLength = lipgloss.Width(completionDescriptions[i])
 		}
 	}
 	maxTitleLength += titlePadding
@@ -115,14 +146,168 @@ func (m Model) showCompletionsRender() string {
 		completionsRender := completionsStyle.Margin(0, 0, 0, offset).Render(completions)
 
 		if m.CompletionsPosition == PositionAbove {
-			return completionsRender + "\n" + m.input.View()
+			return completionsRender + "\n" + m.inputView()
 		}
 		if m.CompletionsPosition == PositionBelow {
-			return m.input.View() + "\n" + completionsRender
+			return m.inputView() + "\n" + completionsRender
+		}
+	}
+
+	return m.inputView()
+}
+
+// canHighlightPaths returns whether the paths in the input can be styled, as the highlights are only up
+// to date with the last parsed input
+func (m Model) canHighlightPaths() bool {
+	return len(m.pathHighlights) > 0 && m.input.Value() == m.lastInput
+}
+
+// inputView renders the input like the text input does, but with any paths styled by whether they exist.
+// It falls back to the text input's own view when the paths can't be highlighted
+func (m Model) inputView() string {
+	if !m.canHighlightPaths() {
+		return m.input.View()
+	}
+	value := []rune(m.input.Value())
+
+	styles := make([]lipgloss.Style, len(value))
+	for i := range styles {
+		styles[i] = m.input.TextStyle
+	}
+	for _, highlight := range m.pathHighlights {
+		for i := highlight.start; i < highlight.end && i < len(styles); i++ {
+			styles[i] = m.pathStyle(highlight.state)
+		}
+	}
+
+	pos := m.input.Position()
+	suggestion := []rune(m.input.CurrentSuggestion())
+	hasSuggestion := m.input.ShowSuggestions && len(suggestion) > len(value)
+
+	v := renderStyledRunes(value[:pos], styles[:pos])
+	if pos < len(value) {
+		m.input.Cursor.TextStyle = styles[pos]
+		m.input.Cursor.SetChar(string(value[pos]))
+		v += m.input.Cursor.View()
+		v += renderStyledRunes(value[pos+1:], styles[pos+1:])
+		if hasSuggestion {
+			v += m.input.PlaceholderStyle.Inline(true).Render(string(suggestion[len(value):]))
+		}
+	} else if hasSuggestion {
+		m.input.Cursor.TextStyle = m.input.CompletionStyle
+		m.input.Cursor.SetChar(string(suggestion[pos]))
+		v += m.input.Cursor.View()
+		v += m.input.PlaceholderStyle.Inline(true).Render(string(suggestion[pos+1:]))
+	} else {
+		m.input.Cursor.SetChar(" ")
+		v += m.input.Cursor.View()
+	}
+
+	return m.input.PromptStyle.Render(m.input.Prompt) + v
+}
+
+// completionTitle returns the name of the completion, struck through if it's deprecated, followed
+// by the aliases of commands and `...` for variadic positional arguments
+func completionTitle(comp Completion) string {
+	name := comp.getName()
+	switch c := comp.(type) {
+	case *PositionalArgument:
+		if c.Variadic {
+			name += "..."
+		}
+	case *Command:
+		if c.Deprecated != "" {
+			name = deprecatedStyle.Render(name)
 		}
+		if len(c.Aliases) > 0 {
+			name += " " + aliasStyle.Render(strings.Join(c.Aliases, ", "))
+		}
+	case *Flag:
+		if c.Deprecated != "" {
+			name = deprecatedStyle.Render(name)
+		}
+	}
+	return name
+}
+
+func (m Model) pathStyle(state pathState) lipgloss.Style {
+	switch state {
+	case pathExists:
+		return m.PathExistsStyle
+	case pathInvalid:
+		return m.PathInvalidStyle
+	case pathNew:
+		return m.input.TextStyle
+	default:
+		return m.PathPrefixStyle
 	}
+}
 
-	return m.input.View()
+// renderStyledRunes renders each rune with the style at the same index
+func renderStyledRunes(runes []rune, styles []lipgloss.Style) string {
+	var b strings.Builder
+	for i, r := range runes {
+		b.WriteString(styles[i].Inline(true).Render(string(r)))
+	}
+	return b.String()
+}
+
+// getPathHighlights returns the range and state of each path entered for a file or directory argument
+func getPathHighlights(p *parsedInput) []pathHighlight {
+	highlights := []pathHighlight{}
+	if p == nil {
+		return highlights
+	}
+	parsed := newParsedCommand(p)
+
+	for _, flag := range p.flags {
+		if !isPathArgument(flag.flag.Type) || !flag.hasValue || flag.value == "" {
+			continue
+		}
+		token := p.tokens[flag.token]
+		start := token.Start
+		if flag.valueToken != -1 {
+			token = p.tokens[flag.valueToken]
+			start = token.Start
+		} else {
+			// Only the value after the equals sign of an inline flag is a path
+			start += utf8.RuneCountInString(token.Raw[:strings.Index(token.Raw, "=")+1])
+		}
+		highlights = append(highlights, pathHighlight{start: start, end: token.End, state: getPathState(p, flag.flag, parsed, flag.value)})
+	}
+
+	for _, positional := range p.positionals {
+		token := p.tokens[positional.token]
+		if !isPathArgument(positional.arg.Type) || token.Value == "" {
+			continue
+		}
+		highlights = append(highlights, pathHighlight{start: token.Start, end: token.End, state: getPathState(p, positional.arg, parsed, token.Value)})
+	}
+
+	return highlights
+}
+
+// getPathState returns whether the path exists or is the start of an existing path, unless it isn't
+// valid for the argument
+func getPathState(p *parsedInput, arg Argument, parsed *ParsedCommand, path string) pathState {
+	ctx := newValueContext(p, arg, parsed)
+	valid := validateArgumentValue(ctx, path) == nil
+	if info, err := statPath(ctx, path); err == nil {
+		if valid {
+			return pathExists
+		}
+		// An existing directory can still be the start of a valid path inside it
+		if !info.IsDir() {
+			return pathInvalid
+		}
+	}
+	if len(completePaths(ctx, path)) > 0 {
+		return pathPrefix
+	}
+	if valid {
+		return pathNew
+	}
+	return pathInvalid
 }
 
 func (m Model) getCompletionsStyle(startCompletionsIndex int, endCompletionsIndex int, rows int) lipgloss.Style {
@@ -147,9 +332,9 @@ func (m Model) calculateCompletionsOffset(completions string) int {
 	}
 
 	input := m.input.Value()
-	parts := splitInput(input)
+	tokens := Tokenize(input)
 
-	if len(parts) == 0 {
+	if len(tokens) == 0 {
 		return 0
 	}
 
@@ -157,13 +342,12 @@ func (m Model) calculateCompletionsOffset(completions string) int {
 
 	// TODO: Offset is slightly off on each new line
 
-	// If we're about to start typing a new part, set the offset to the end of the string
-	if strings.HasSuffix(input, " ") && (!strings.Contains(parts[len(parts)-1], " ") || stringEndsInQuote(parts[len(parts)-1])) {
+	// If we're about to start typing a new token, set the offset to the end of the string
+	if endsWithSeparator(input) {
 		offset = (lipgloss.Width(input) % m.width)
 	} else {
-		// If we're typing, set the offset to the end of the last part
-		trimmedInput := input[:strings.LastIndex(input, parts[len(parts)-1])]
-		offset = lipgloss.Width(trimmedInput) % m.width
+		// If we're typing, set the offset to the start of the token being typed
+		offset = lipgloss.Width(input[:tokens[len(tokens)-1].ByteStart]) % m.width
 	}
 
 	offset += m.CompletionsOffset
@@ -185,10 +369,3 @@ func (m Model) getCompletionsWidth(maxLineLength int) int {
 	}
 	return maxLineLength
 }
-
-func stringEndsInQuote(s string) bool {
-	if strings.HasSuffix(s, "\"") || strings.HasSuffix(s, "'") {
-		return true
-	}
-	return false
-}
@@ -1,24 +1,146 @@
 package bubblecomplete
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
 
-func TestStringEndsInQuote(t *testing.T) {
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestCalculateCompletionsOffset(t *testing.T) {
 	cases := []struct {
 		input    string
-		expected bool
+		expected int
 	}{
-		{"Hello World", false},
-		{"Hello World\"", true},
-		{"Hello World'", true},
-		{"Hello \"World\"", true},
-		{"Hello 'World'", true},
-		{"Hello", false},
+		{"git", 0},
+		{"git ", 4},
+		{"git com", 4},
+		{"cat My\\ Doc", 4},
+		{"cat My\\ Documents ", 18},
+		{"git commit -m \"hello wor", 14},
+	}
+
+	m, err := New([]*Command{}, 100)
+	if err != nil {
+		t.Fatal(err)
 	}
 
 	for _, c := range cases {
-		result := stringEndsInQuote(c.input)
+		m.input.SetValue(c.input)
+		result := m.calculateCompletionsOffset("")
 		if result != c.expected {
-			t.Errorf("stringEndsInQuote(%q) == %t, expected %t", c.input, result, c.expected)
+			t.Errorf("calculateCompletionsOffset() with input %q == %d, expected %d", c.input, result, c.expected)
+		}
+	}
+}
+
+func TestGetPathHighlights(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := []*Command{
+		{
+			Command: "cat",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileArgument},
+			},
+			Flags: []*Flag{
+				{LongFlag: "--out", Type: DirArgument},
+			},
+		},
+		{
+			Command: "touch",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileArgument, Existence: PathMustNotExist},
+			},
+		},
+	}
+
+	cases := []struct {
+		input    string
+		expected pathState
+	}{
+		{"cat " + dir + "/notes.txt", pathExists},
+		{"cat " + dir + "/no", pathPrefix},
+		{"cat " + dir + "/missing", pathInvalid},
+		{"cat " + dir, pathPrefix},
+		{"cat --out " + dir, pathExists},
+		{"cat --out " + dir + "/notes.txt", pathInvalid},
+		{"touch " + dir + "/notes.txt", pathInvalid},
+		{"touch " + dir + "/new.txt", pathNew},
+		{"touch " + dir + "/no", pathNew},
+	}
+
+	for _, c := range cases {
+		highlights := getPathHighlights(parseInput(c.input, commands))
+		if len(highlights) != 1 || highlights[0].state != c.expected {
+			t.Errorf("getPathHighlights(%q) == %+v, expected state %d", c.input, highlights, c.expected)
+		}
+	}
+
+	highlights := getPathHighlights(parseInput("cat --out="+dir+" 'my file'", commands))
+	expected := []pathHighlight{
+		{start: 10, end: 10 + len(dir), state: pathExists},
+		{start: 11 + len(dir), end: 20 + len(dir), state: pathInvalid},
+	}
+	if !slices.Equal(highlights, expected) {
+		t.Errorf("getPathHighlights() == %+v, expected %+v", highlights, expected)
+	}
+}
+
+func TestInputViewPathHighlights(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := []*Command{
+		{
+			Command: "cat",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileArgument},
+			},
+		},
+	}
+	input := "cat " + dir + "/notes.txt"
+
+	cases := []struct {
+		name      string
+		setup     func(m *Model)
+		highlight bool
+	}{
+		{"normal", func(m *Model) {}, true},
+		{"stale", func(m *Model) { m.input.SetValue(input + "x") }, false},
+	}
+
+	for _, c := range cases {
+		m, err := New(commands, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.PathExistsStyle = lipgloss.NewStyle().Transform(strings.ToUpper)
+		m.input.SetValue(input)
+		m.inputChanged()
+		c.setup(&m)
+
+		if result := m.canHighlightPaths(); result != c.highlight {
+			t.Errorf("%s: canHighlightPaths() == %t, expected %t", c.name, result, c.highlight)
+		}
+		view := m.inputView()
+		if !c.highlight {
+			if expected := m.input.View(); view != expected {
+				t.Errorf("%s: inputView() == %q, expected the text input's view %q", c.name, view, expected)
+			}
+			continue
+		}
+		expected := m.input.Prompt + "cat " + strings.ToUpper(dir+"/notes.txt") + " "
+		if view != expected {
+			t.Errorf("%s: inputView() == %q, expected %q", c.name, view, expected)
 		}
 	}
 }
@@ -0,0 +1,387 @@
+package bubblecomplete
+
+import (
+	"fmt"
+	"io/fs"
+	"net/netip"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MARK: Types and Vars
+
+// ParsedCommand is the structured result of parsing an entered command
+type ParsedCommand struct {
+	// The resolved commands, from the root command to the deepest subcommand
+	Commands []*Command
+	// The flag values entered, keyed by both the short and long flag i.e. `-m` and `--message`
+	//
+	// Bool flags entered without a value are set to "true", and count flags to the number of times
+	// they were entered. Repeatable flags are set to their last value
+	Flags map[string]string
+	// Every value of the flags entered, in the order they were entered, keyed like Flags
+	FlagValues map[string][]string
+	// The positional argument values entered, keyed by the positional argument name
+	//
+	// Variadic arguments are set to their last value
+	Positionals map[string]string
+	// Every value of the positional arguments entered, in the order they were entered, keyed like Positionals
+	PositionalValues map[string][]string
+	// The directory relative paths were resolved from, the WorkingDir of the model
+	WorkingDir string
+	// The filesystem paths were resolved against, nil for the OS filesystem
+	fsys fs.FS
+}
+
+// MARK: Public Functions
+
+// Command returns the deepest command resolved, or nil if no command was resolved
+func (p *ParsedCommand) Command() *Command {
+	if len(p.Commands) == 0 {
+		return nil
+	}
+	return p.Commands[len(p.Commands)-1]
+}
+
+// Has returns true if the flag or positional argument with the given name was entered
+func (p *ParsedCommand) Has(name string) bool {
+	_, ok := p.lookup(name)
+	return ok
+}
+
+// String returns the value of the flag or positional argument with the given name
+//
+// Returns an empty string if it wasn't entered
+func (p *ParsedCommand) String(name string) string {
+	value, _ := p.lookup(name)
+	return value
+}
+
+// Strings returns every value of the flag or positional argument with the given name, in the order they were entered
+//
+// Returns nil if it wasn't entered
+func (p *ParsedCommand) Strings(name string) []string {
+	if values, ok := p.FlagValues[name]; ok {
+		return values
+	}
+	return p.PositionalValues[name]
+}
+
+// Count returns the number of times the flag was entered, or the number of values of the
+// positional argument, with the given name
+func (p *ParsedCommand) Count(name string) int {
+	return len(p.Strings(name))
+}
+
+// Int returns the value of the flag or positional argument with the given name as an int
+//
+// Returns an error if it wasn't entered or isn't a valid integer
+func (p *ParsedCommand) Int(name string) (int, error) {
+	value, err := p.require(name)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer value for argument: %s", name)
+	}
+	return i, nil
+}
+
+// Float returns the value of the flag or positional argument with the given name as a float64
+//
+// Returns an error if it wasn't entered or isn't a valid float
+func (p *ParsedCommand) Float(name string) (float64, error) {
+	value, err := p.require(name)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid float value for argument: %s", name)
+	}
+	return f, nil
+}
+
+// Bool returns the value of the flag or positional argument with the given name as a bool
+//
+// Returns false if it wasn't entered, or an error if the value isn't a valid boolean. Count flags
+// are true if they were entered
+func (p *ParsedCommand) Bool(name string) (bool, error) {
+	value, ok := p.lookup(name)
+	if !ok {
+		return false, nil
+	}
+	if flag, ok := p.flagDefinition(name).(*Flag); ok && flag.Count {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid boolean value for argument: %s", name)
+	}
+	return b, nil
+}
+
+// Path returns the value of the flag or positional argument with the given name as a cleaned file path
+//
+// The path is resolved the same way it was validated. A leading `~` is expanded to the user's home
+// directory and relative paths are joined to the WorkingDir. If the model has an FS, the path is
+// instead the name in the FS, as used with fs.Open. Returns an empty string if it wasn't entered
+func (p *ParsedCommand) Path(name string) string {
+	value, ok := p.lookup(name)
+	if !ok || value == "" {
+		return ""
+	}
+	ctx := ValueContext{FS: p.fsys, WorkingDir: p.WorkingDir}
+	if p.fsys == nil {
+		return filepath.Clean(osPath(ctx, value))
+	}
+	fsName, err := fsPath(ctx, value)
+	if err != nil {
+		return path.Clean(value)
+	}
+	return fsName
+}
+
+// Duration returns the value of the flag or positional argument with the given name as a time.Duration
+//
+// Returns an error if it wasn't entered or isn't a valid duration
+func (p *ParsedCommand) Duration(name string) (time.Duration, error) {
+	value, err := p.require(name)
+	if err != nil {
+		return 0, err
+	}
+	d, err := parseDuration(value)
+	if err != nil {
+		return 0, invalidValueError("duration", name, err)
+	}
+	return d, nil
+}
+
+// URL returns the value of the flag or positional argument with the given name as a URL
+//
+// Returns an error if it wasn't entered or isn't an absolute URL
+func (p *ParsedCommand) URL(name string) (*url.URL, error) {
+	value, err := p.require(name)
+	if err != nil {
+		return nil, err
+	}
+	u, err := parseURL(value)
+	if err != nil {
+		return nil, invalidValueError("URL", name, err)
+	}
+	return u, nil
+}
+
+// IP returns the value of the flag or positional argument with the given name as an IP address
+//
+// Returns an error if it wasn't entered or isn't a valid IPv4 or IPv6 address
+func (p *ParsedCommand) IP(name string) (netip.Addr, error) {
+	value, err := p.require(name)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	addr, err := parseIP(value)
+	if err != nil {
+		return netip.Addr{}, invalidValueError("IP address", name, err)
+	}
+	return addr, nil
+}
+
+// CIDR returns the value of the flag or positional argument with the given name as an IP address prefix
+//
+// Returns an error if it wasn't entered or isn't valid CIDR notation
+func (p *ParsedCommand) CIDR(name string) (netip.Prefix, error) {
+	value, err := p.require(name)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	prefix, err := parseCIDR(value)
+	if err != nil {
+		return netip.Prefix{}, invalidValueError("CIDR", name, err)
+	}
+	return prefix, nil
+}
+
+// Time returns the value of the flag or positional argument with the given name as a time.Time
+//
+// The value is parsed with the layout of the flag or positional argument, or RFC 3339 if it
+// doesn't have one. Returns an error if it wasn't entered or doesn't match the layout
+func (p *ParsedCommand) Time(name string) (time.Time, error) {
+	value, err := p.require(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	layout := ""
+	if arg := p.definition(name); arg != nil {
+		layout = arg.getLayout()
+	}
+	t, err := parseTime(value, layout)
+	if err != nil {
+		return time.Time{}, invalidValueError("time", name, err)
+	}
+	return t, nil
+}
+
+// ByteSize returns the value of the flag or positional argument with the given name as a number of bytes
+//
+// Returns an error if it wasn't entered or isn't a valid byte size
+func (p *ParsedCommand) ByteSize(name string) (int64, error) {
+	value, err := p.require(name)
+	if err != nil {
+		return 0, err
+	}
+	size, err := parseByteSize(value)
+	if err != nil {
+		return 0, invalidValueError("byte size", name, err)
+	}
+	return size, nil
+}
+
+// Regex returns the value of the flag or positional argument with the given name as a compiled regular expression
+//
+// Returns an error if it wasn't entered or isn't a valid regular expression
+func (p *ParsedCommand) Regex(name string) (*regexp.Regexp, error) {
+	value, err := p.require(name)
+	if err != nil {
+		return nil, err
+	}
+	re, err := parseRegex(value)
+	if err != nil {
+		return nil, invalidValueError("regular expression", name, err)
+	}
+	return re, nil
+}
+
+// JSON unmarshals the value of the flag or positional argument with the given name into v
+//
+// Returns an error if it wasn't entered or can't be unmarshalled into v
+func (p *ParsedCommand) JSON(name string, v any) error {
+	value, err := p.require(name)
+	if err != nil {
+		return err
+	}
+	if err := parseJSON(value, v); err != nil {
+		return invalidValueError("JSON", name, err)
+	}
+	return nil
+}
+
+// MARK: Private Functions
+
+func newParsedCommand(p *parsedInput) *ParsedCommand {
+	return buildParsedCommand(p, p.commands, p.flags, p.positionals)
+}
+
+// newParsedCommandBeforeCursor returns the command entered before the cursor, excluding
+// any flag waiting for the value at the cursor
+func newParsedCommandBeforeCursor(p *parsedInput) *ParsedCommand {
+	flags := p.flags[:p.cursor.flagCount]
+	if p.cursor.pendingFlag != nil {
+		flags = flags[:len(flags)-1]
+	}
+	return buildParsedCommand(p, p.commands[:p.cursor.depth], flags, p.positionals[:p.cursor.positionalCount])
+}
+
+func buildParsedCommand(p *parsedInput, commands []*Command, flags []*parsedFlag, positionals []*parsedPositional) *ParsedCommand {
+	result := &ParsedCommand{
+		Commands:         append([]*Command{}, commands...),
+		Flags:            map[string]string{},
+		FlagValues:       map[string][]string{},
+		Positionals:      map[string]string{},
+		PositionalValues: map[string][]string{},
+		WorkingDir:       p.workingDir,
+		fsys:             p.fsys,
+	}
+
+	for _, parsed := range flags {
+		value := parsed.value
+		if parsed.flag.Type == BoolArgument && !parsed.hasValue {
+			value = "true"
+		}
+		for _, name := range []string{parsed.flag.ShortFlag, parsed.flag.LongFlag} {
+			if name == "" {
+				continue
+			}
+			result.FlagValues[name] = append(result.FlagValues[name], value)
+			result.Flags[name] = value
+			if parsed.flag.Count {
+				result.Flags[name] = strconv.Itoa(len(result.FlagValues[name]))
+			}
+		}
+	}
+
+	for _, parsed := range positionals {
+		value := p.tokens[parsed.token].Value
+		result.Positionals[parsed.arg.Name] = value
+		result.PositionalValues[parsed.arg.Name] = append(result.PositionalValues[parsed.arg.Name], value)
+	}
+
+	return result
+}
+
+func (p *ParsedCommand) lookup(name string) (string, bool) {
+	if value, ok := p.Flags[name]; ok {
+		return value, true
+	}
+	value, ok := p.Positionals[name]
+	return value, ok
+}
+
+// flagDefinition returns the flag with the given short or long flag from the resolved commands
+func (p *ParsedCommand) flagDefinition(name string) Argument {
+	for _, cmd := range p.Commands {
+		if flag, err := findFlag(cmd.Flags, name); err == nil {
+			return flag
+		}
+	}
+	return nil
+}
+
+// positionalDefinition returns the positional argument with the given name from the deepest command
+func (p *ParsedCommand) positionalDefinition(name string) Argument {
+	cmd := p.Command()
+	if cmd == nil {
+		return nil
+	}
+	for _, arg := range cmd.PositionalArguments {
+		if arg.Name == name {
+			return arg
+		}
+	}
+	return nil
+}
+
+// definition returns the flag or positional argument with the given name, if it's defined
+func (p *ParsedCommand) definition(name string) Argument {
+	if arg := p.flagDefinition(name); arg != nil {
+		return arg
+	}
+	return p.positionalDefinition(name)
+}
+
+func (p *ParsedCommand) require(name string) (string, error) {
+	value, ok := p.lookup(name)
+	if !ok {
+		return "", fmt.Errorf("no value entered for argument: %s", name)
+	}
+	return value, nil
+}
+
+// expandHome replaces a leading `~` in the path with the user's home directory
+func expandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
@@ -0,0 +1,165 @@
+package bubblecomplete
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNewParsedCommand(t *testing.T) {
+	result := newParsedCommand(parseInput("git commit -am \"hello world\" --help", testCommands()))
+
+	if result.Command() == nil || result.Command().Command != "commit" || len(result.Commands) != 2 {
+		t.Fatalf("unexpected commands %v", result.Commands)
+	}
+
+	if result.String("-m") != "hello world" || result.String("--message") != "hello world" {
+		t.Errorf("String(\"-m\") == %q, expected %q", result.String("-m"), "hello world")
+	}
+
+	for _, name := range []string{"-a", "--all", "--help"} {
+		if b, err := result.Bool(name); !b || err != nil {
+			t.Errorf("Bool(%q) == %t, %v, expected true", name, b, err)
+		}
+	}
+
+	if b, err := result.Bool("--amend"); b || err != nil {
+		t.Errorf("Bool(\"--amend\") == %t, %v, expected false", b, err)
+	}
+}
+
+func TestParsedCommandTypedValues(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "resize",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileArgument},
+				{Name: "width", Type: IntArgument},
+				{Name: "scale", Type: FloatArgument},
+			},
+		},
+	}
+	result := newParsedCommand(parseInput("resize ./images//cat.png 640 1.5", commands))
+
+	if width, err := result.Int("width"); width != 640 || err != nil {
+		t.Errorf("Int(\"width\") == %d, %v, expected 640", width, err)
+	}
+	if scale, err := result.Float("scale"); scale != 1.5 || err != nil {
+		t.Errorf("Float(\"scale\") == %f, %v, expected 1.5", scale, err)
+	}
+	if path := result.Path("file"); path != "images/cat.png" {
+		t.Errorf("Path(\"file\") == %q, expected %q", path, "images/cat.png")
+	}
+	if _, err := result.Int("file"); err == nil {
+		t.Errorf("Int(\"file\") expected an error")
+	}
+	if _, err := result.Int("height"); err == nil {
+		t.Errorf("Int(\"height\") expected an error")
+	}
+}
+
+func TestParsedCommandRichValues(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "deploy",
+			Flags: []*Flag{
+				{LongFlag: "--timeout", Type: DurationArgument},
+				{LongFlag: "--endpoint", Type: URLArgument},
+				{LongFlag: "--subnet", Type: CIDRArgument},
+				{LongFlag: "--date", Type: TimeArgument, Layout: "2006-01-02"},
+				{LongFlag: "--size", Type: ByteSizeArgument},
+				{LongFlag: "--spec", Type: JSONArgument},
+			},
+		},
+	}
+	input := "deploy --timeout 90s --endpoint https://example.com:8443 --subnet 10.0.0.0/8 --date 2024-05-01 --size 10MiB --spec '{\"replicas\": 3}'"
+	result := newParsedCommand(parseInput(input, commands))
+
+	if d, err := result.Duration("--timeout"); d != 90*time.Second || err != nil {
+		t.Errorf("Duration() == %v, %v, expected 1m30s", d, err)
+	}
+	if u, err := result.URL("--endpoint"); err != nil || u.Port() != "8443" {
+		t.Errorf("URL() == %v, %v, expected port 8443", u, err)
+	}
+	if prefix, err := result.CIDR("--subnet"); err != nil || prefix.Bits() != 8 {
+		t.Errorf("CIDR() == %v, %v, expected 10.0.0.0/8", prefix, err)
+	}
+	if date, err := result.Time("--date"); err != nil || date.Month() != time.May || date.Day() != 1 {
+		t.Errorf("Time() == %v, %v, expected 2024-05-01", date, err)
+	}
+	if size, err := result.ByteSize("--size"); size != 10<<20 || err != nil {
+		t.Errorf("ByteSize() == %d, %v, expected %d", size, err, 10<<20)
+	}
+
+	var spec struct{ Replicas int }
+	if err := result.JSON("--spec", &spec); err != nil || spec.Replicas != 3 {
+		t.Errorf("JSON() == %+v, %v, expected 3 replicas", spec, err)
+	}
+	if _, err := result.Duration("--endpoint"); err == nil {
+		t.Errorf("Duration(\"--endpoint\") expected an error")
+	}
+}
+
+func TestParsedCommandRepeatedFlags(t *testing.T) {
+	result := newParsedCommand(parseInput("build -vvv --tag a -t b --output x --output y", testRepeatableFlagCommands()))
+
+	if count := result.Count("--verbose"); count != 3 {
+		t.Errorf("Count(\"--verbose\") == %d, expected 3", count)
+	}
+	if verbose, err := result.Int("-v"); verbose != 3 || err != nil {
+		t.Errorf("Int(\"-v\") == %d, %v, expected 3", verbose, err)
+	}
+	if verbose, err := result.Bool("-v"); !verbose || err != nil {
+		t.Errorf("Bool(\"-v\") == %t, %v, expected true", verbose, err)
+	}
+
+	if tags := result.Strings("--tag"); !slices.Equal(tags, []string{"a", "b"}) {
+		t.Errorf("Strings(\"--tag\") == %q, expected [a b]", tags)
+	}
+	if tag := result.String("-t"); tag != "b" {
+		t.Errorf("String(\"-t\") == %q, expected the last value", tag)
+	}
+	if output := result.String("--output"); output != "y" {
+		t.Errorf("String(\"--output\") == %q, expected the last value", output)
+	}
+	if ports := result.Strings("--port"); ports != nil {
+		t.Errorf("Strings(\"--port\") == %q, expected nil", ports)
+	}
+}
+
+func TestParsedCommandVariadicValues(t *testing.T) {
+	result := newParsedCommand(parseInput("join , 1 2 3", testVariadicCommands()))
+
+	if parts := result.Strings("part"); !slices.Equal(parts, []string{"1", "2", "3"}) {
+		t.Errorf("Strings(\"part\") == %q, expected [1 2 3]", parts)
+	}
+	if count := result.Count("part"); count != 3 {
+		t.Errorf("Count(\"part\") == %d, expected 3", count)
+	}
+	if separator := result.Strings("separator"); !slices.Equal(separator, []string{","}) {
+		t.Errorf("Strings(\"separator\") == %q, expected [,]", separator)
+	}
+}
+
+func TestParsedCommandPathWorkingDir(t *testing.T) {
+	commands := testPathCommands()
+
+	p := parseInput("cp images/cat.png /tmp", commands)
+	p.workingDir = filepath.Join("srv", "app")
+	result := newParsedCommand(p)
+	if path := result.Path("file"); path != filepath.Join("srv", "app", "images", "cat.png") {
+		t.Errorf("Path(\"file\") == %q, expected it joined to the working directory", path)
+	}
+	if path := result.Path("destination"); path != "/tmp" {
+		t.Errorf("Path(\"destination\") == %q, expected the absolute path unchanged", path)
+	}
+
+	result = newParsedCommand(parseFSInput("cp docs/../notes.txt /etc"))
+	if path := result.Path("file"); path != "home/notes.txt" {
+		t.Errorf("Path(\"file\") == %q, expected the name in the FS", path)
+	}
+	if path := result.Path("destination"); path != "etc" {
+		t.Errorf("Path(\"destination\") == %q, expected the name in the FS", path)
+	}
+}
@@ -6,6 +6,7 @@ import "github.com/charmbracelet/lipgloss"
 var (
 	green        = lipgloss.AdaptiveColor{Light: "#02BA84", Dark: "#02BF87"}
 	pink         = lipgloss.AdaptiveColor{Light: "#FF2C70", Dark: "#FF2C70"}
+	red          = lipgloss.AdaptiveColor{Light: "#D7263D", Dark: "#FF5F5F"}
 	pinkBg       = lipgloss.AdaptiveColor{Light: "#19040b", Dark: "#19040b"}
 	bluegray     = lipgloss.AdaptiveColor{Light: "#5C6773", Dark: "#1f262d"}
 	darkBluegray = lipgloss.AdaptiveColor{Light: "#3D4852", Dark: "#12161B"}
@@ -17,6 +18,9 @@ var (
 var (
 	lg                            = lipgloss.NewStyle()
 	highlightedCompletionStyle    = lg.Foreground(pink).Background(pinkBg).Bold(true)
+	aliasStyle                    = lg.Faint(true)
+	deprecatedStyle               = lg.Strikethrough(true)
+	pickerErrorStyle              = lg.Foreground(red)
 	completionRowStyle            = lg.Background(bluegray)
 	altCompletionRowStyle         = lg.Background(darkBluegray)
 	completionsBoxStyle           = lg.Border(lipgloss.RoundedBorder()).BorderStyle(lipgloss.ThickBorder()).BorderForeground(bluegray)
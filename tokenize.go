@@ -0,0 +1,168 @@
+package bubblecomplete
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MARK: Types and Vars
+
+// Token is a single word of the input, as split by Tokenize
+type Token struct {
+	// The text of the token exactly as it was typed, including any quotes and escapes
+	Raw string
+	// The value of the token with quotes removed and escapes resolved
+	Value string
+	// The rune offset of the first character of the token in the input
+	Start int
+	// The rune offset directly after the last character of the token in the input
+	End int
+	// The byte offset of the first character of the token in the input
+	ByteStart int
+	// The byte offset directly after the last character of the token in the input
+	ByteEnd int
+	// The first quote character used in the token, or 0 if the token isn't quoted
+	Quote rune
+	// Whether the input ended inside a quote or directly after an escape character
+	Unclosed bool
+}
+
+// Quoted returns true if any part of the token was wrapped in quotes
+func (t Token) Quoted() bool {
+	return t.Quote != 0
+}
+
+// MARK: Public Functions
+
+// Tokenize splits the input into tokens using shell-like rules
+//
+// Tokens are separated by unquoted whitespace. Single quotes preserve everything up to
+// the closing quote, double quotes preserve everything except for escaped `"` and `\`
+// characters, and outside of quotes a backslash escapes the character after it, so
+// `My\ Documents` is a single token with the value `My Documents`.
+func Tokenize(input string) []Token {
+	var tokens []Token
+	var raw, value strings.Builder
+	var current Token
+	inToken := false
+	escaped := false
+	var quoteChar rune
+	runeIndex := 0
+
+	startToken := func(byteIndex int) {
+		if inToken {
+			return
+		}
+		inToken = true
+		current = Token{Start: runeIndex, ByteStart: byteIndex}
+	}
+
+	flushToken := func(byteIndex int) {
+		if !inToken {
+			return
+		}
+		current.Raw = raw.String()
+		current.Value = value.String()
+		current.End = runeIndex
+		current.ByteEnd = byteIndex
+		tokens = append(tokens, current)
+		raw.Reset()
+		value.Reset()
+		inToken = false
+	}
+
+	for byteIndex, char := range input {
+		switch {
+		case escaped:
+			// Inside double quotes only a quote or backslash can be escaped
+			if quoteChar == '"' && char != '"' && char != '\\' {
+				value.WriteRune('\\')
+			}
+			value.WriteRune(char)
+			escaped = false
+		case quoteChar == '\'':
+			if char == '\'' {
+				quoteChar = 0
+			} else {
+				value.WriteRune(char)
+			}
+		case char == '\\':
+			startToken(byteIndex)
+			escaped = true
+		case quoteChar == '"':
+			if char == '"' {
+				quoteChar = 0
+			} else {
+				value.WriteRune(char)
+			}
+		case char == '"' || char == '\'':
+			startToken(byteIndex)
+			quoteChar = char
+			if current.Quote == 0 {
+				current.Quote = char
+			}
+		case unicode.IsSpace(char):
+			flushToken(byteIndex)
+		default:
+			startToken(byteIndex)
+			value.WriteRune(char)
+		}
+
+		if inToken {
+			raw.WriteRune(char)
+		}
+		runeIndex++
+	}
+
+	// A trailing escape keeps its backslash as there's nothing left for it to escape
+	if escaped {
+		value.WriteRune('\\')
+	}
+	current.Unclosed = escaped || quoteChar != 0
+	flushToken(len(input))
+
+	return tokens
+}
+
+// MARK: Private Functions
+
+// tokenRaws returns the raw text of each token
+func tokenRaws(tokens []Token) []string {
+	raws := make([]string, len(tokens))
+	for i, token := range tokens {
+		raws[i] = token.Raw
+	}
+	return raws
+}
+
+// endsWithSeparator returns true if the input ends with whitespace that isn't part of the final token
+//
+// i.e. the user has finished typing the last token and is about to start a new one
+func endsWithSeparator(input string) bool {
+	if input == "" {
+		return false
+	}
+	tokens := Tokenize(input)
+	if len(tokens) == 0 {
+		return true
+	}
+	return tokens[len(tokens)-1].ByteEnd < len(input)
+}
+
+// quoteValue wraps the value in quotes if Tokenize would otherwise split or alter it
+func quoteValue(value string) string {
+	needsQuotes := value == "" || strings.ContainsFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '"' || r == '\'' || r == '\\'
+	})
+	if !needsQuotes {
+		return value
+	}
+
+	if !strings.ContainsAny(value, "\"\\") {
+		return "\"" + value + "\""
+	}
+	if !strings.ContainsRune(value, '\'') {
+		return "'" + value + "'"
+	}
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(value) + "\""
+}
@@ -0,0 +1,102 @@
+package bubblecomplete
+
+import "testing"
+
+func TestTokenizeValues(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected []string
+	}{
+		{"git stash pop", []string{"git", "stash", "pop"}},
+		{"git  commit   -m \"hello world\"", []string{"git", "commit", "-m", "hello world"}},
+		{"cat 'my file'", []string{"cat", "my file"}},
+		{"cat My\\ Documents/file.txt", []string{"cat", "My Documents/file.txt"}},
+		{"--message=\"hello world\" --amend", []string{"--message=hello world", "--amend"}},
+		{"echo \"say \\\"hi\\\"\"", []string{"echo", "say \"hi\""}},
+		{"echo \"a\\b\"", []string{"echo", "a\\b"}},
+		{"echo 'a\\b'", []string{"echo", "a\\b"}},
+		{"echo \"\"", []string{"echo", ""}},
+		{"cat '\"'\" --help", []string{"cat", "\" --help"}},
+		{"cd dir\\", []string{"cd", "dir\\"}},
+	}
+
+	for _, c := range cases {
+		tokens := Tokenize(c.input)
+		if len(tokens) != len(c.expected) {
+			t.Errorf("Tokenize(%q) returned %d tokens, expected %d", c.input, len(tokens), len(c.expected))
+			continue
+		}
+		for i, token := range tokens {
+			if token.Value != c.expected[i] {
+				t.Errorf("Tokenize(%q)[%d].Value == %q, expected %q", c.input, i, token.Value, c.expected[i])
+			}
+		}
+	}
+}
+
+func TestTokenizeSpans(t *testing.T) {
+	input := "cp \"héllo wörld\" My\\ Docs"
+	tokens := Tokenize(input)
+	if len(tokens) != 3 {
+		t.Fatalf("Tokenize(%q) returned %d tokens, expected 3", input, len(tokens))
+	}
+
+	for _, token := range tokens {
+		if input[token.ByteStart:token.ByteEnd] != token.Raw {
+			t.Errorf("byte span of %q is %q", token.Raw, input[token.ByteStart:token.ByteEnd])
+		}
+		if string([]rune(input)[token.Start:token.End]) != token.Raw {
+			t.Errorf("rune span of %q is %q", token.Raw, string([]rune(input)[token.Start:token.End]))
+		}
+	}
+
+	if tokens[1].Quote != '"' || !tokens[1].Quoted() {
+		t.Errorf("expected %q to be double quoted", tokens[1].Raw)
+	}
+	if tokens[2].Quoted() {
+		t.Errorf("expected %q to not be quoted", tokens[2].Raw)
+	}
+}
+
+func TestTokenizeUnclosed(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected bool
+	}{
+		{"git commit -m \"hello", true},
+		{"git commit -m 'hello world", true},
+		{"git commit -m \"hello\"", false},
+		{"cat My\\", true},
+		{"cat My\\ ", false},
+	}
+
+	for _, c := range cases {
+		tokens := Tokenize(c.input)
+		last := tokens[len(tokens)-1]
+		if last.Unclosed != c.expected {
+			t.Errorf("Tokenize(%q) last token Unclosed == %t, expected %t", c.input, last.Unclosed, c.expected)
+		}
+	}
+}
+
+func TestEndsWithSeparator(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected bool
+	}{
+		{"", false},
+		{" ", true},
+		{"git", false},
+		{"git ", true},
+		{"cat My\\ ", false},
+		{"git commit -m \"hello ", false},
+		{"git commit -m \"hello\" ", true},
+	}
+
+	for _, c := range cases {
+		result := endsWithSeparator(c.input)
+		if result != c.expected {
+			t.Errorf("endsWithSeparator(%q) == %t, expected %t", c.input, result, c.expected)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package bubblecomplete
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"sync"
+)
+
+// MARK: Types and Vars
+
+// ArgumentType is the type of value a flag or positional argument takes, i.e. "int"
+//
+// Types other than the built-in ones must be registered with RegisterArgumentType.
+type ArgumentType string
+
+const (
+	StringArgument  ArgumentType = "string"
+	IntArgument     ArgumentType = "int"
+	FloatArgument   ArgumentType = "float"
+	BoolArgument    ArgumentType = "bool"
+	FileArgument    ArgumentType = "file"
+	DirArgument     ArgumentType = "dir"
+	FileDirArgument ArgumentType = "filedir"
+	ChoiceArgument  ArgumentType = "choice"
+	// A duration such as `30s` or `1h30m`, parsed with time.ParseDuration
+	DurationArgument ArgumentType = "duration"
+	// An absolute URL with a scheme and host, such as `https://example.com`
+	URLArgument ArgumentType = "url"
+	// An IPv4 or IPv6 address, such as `192.168.0.1`
+	IPArgument ArgumentType = "ip"
+	// An IP address prefix in CIDR notation, such as `10.0.0.0/8`
+	CIDRArgument ArgumentType = "cidr"
+	// A date or time in the layout of the flag or positional argument, RFC 3339 by default
+	TimeArgument ArgumentType = "time"
+	// A byte size with an optional SI or binary unit, such as `512KB` or `10MiB`
+	ByteSizeArgument ArgumentType = "bytesize"
+	// A regular expression, parsed with regexp.Compile
+	RegexArgument ArgumentType = "regex"
+	// A JSON value, such as `{"replicas": 3}`
+	JSONArgument ArgumentType = "json"
+)
+
+// ArgumentTypeDefinition defines how the values of an argument type are validated, completed and shown
+type ArgumentTypeDefinition struct {
+	// The label shown in the descriptions of flags and positional arguments of the type, i.e. "int"
+	//
+	// An empty label isn't shown.
+	Label string
+	// Returns an error if the value entered isn't valid for the type
+	Validate func(ctx ValueContext, value string) error
+	// Optional function returning the values to suggest, used when the flag or positional
+	// argument doesn't have its own completer
+	Complete func(ctx ValueContext, partial string) []Suggestion
+}
+
+// ValueContext holds the flag or positional argument a value is entered for
+type ValueContext struct {
+	// The *Flag or *PositionalArgument the value is for
+	Argument Argument
+	// The name of the flag or positional argument, for use in error messages
+	Name string
+	// The command entered before the value
+	Parsed *ParsedCommand
+	// The filesystem paths are resolved against, nil for the OS filesystem
+	FS fs.FS
+	// The directory relative paths are resolved from, the current directory if empty
+	WorkingDir string
+}
+
+var (
+	argumentTypesMu sync.RWMutex
+	argumentTypes   = map[ArgumentType]ArgumentTypeDefinition{
+		StringArgument:  {Label: "string", Validate: validateStringArgument},
+		IntArgument:     {Label: "int", Validate: validateIntArgument},
+		FloatArgument:   {Label: "float", Validate: validateFloatArgument},
+		BoolArgument:    {Validate: validateBoolArgument},
+		FileArgument:    {Label: "file", Validate: validateFileArgument, Complete: completePaths},
+		DirArgument:     {Label: "dir", Validate: validateDirArgument, Complete: completePaths},
+		FileDirArgument: {Label: "filedir", Validate: validateFileDirArgument, Complete: completePaths},
+		ChoiceArgument:  {Label: "choice", Validate: validateChoiceArgument, Complete: completeChoices},
+
+		DurationArgument: {Label: "duration", Validate: validateDurationArgument},
+		URLArgument:      {Label: "url", Validate: validateURLArgument},
+		IPArgument:       {Label: "ip", Validate: validateIPArgument},
+		CIDRArgument:     {Label: "cidr", Validate: validateCIDRArgument},
+		TimeArgument:     {Label: "time", Validate: validateTimeArgument},
+		ByteSizeArgument: {Label: "size", Validate: validateByteSizeArgument},
+		RegexArgument:    {Label: "regex", Validate: validateRegexArgument},
+		JSONArgument:     {Label: "json", Validate: validateJSONArgument},
+	}
+)
+
+// MARK: Public Functions
+
+// RegisterArgumentType adds an argument type that flags and positional arguments can use
+//
+// Types must be registered before creating the model with any commands that use them.
+// Returns an error if the type is already registered or has no validator.
+func RegisterArgumentType(argType ArgumentType, definition ArgumentTypeDefinition) error {
+	if argType == "" {
+		return errors.New("argument types must have a name")
+	}
+	if definition.Validate == nil {
+		return errors.New("argument types must have a validator")
+	}
+
+	argumentTypesMu.Lock()
+	defer argumentTypesMu.Unlock()
+
+	if _, ok := argumentTypes[argType]; ok {
+		return fmt.Errorf("argument type '%s' is already registered", argType)
+	}
+	argumentTypes[argType] = definition
+	return nil
+}
+
+// MARK: Private Functions
+
+func lookupArgumentType(argType ArgumentType) (ArgumentTypeDefinition, bool) {
+	argumentTypesMu.RLock()
+	defer argumentTypesMu.RUnlock()
+
+	definition, ok := argumentTypes[argType]
+	return definition, ok
+}
+
+// argumentLabel returns the label of the argument type, or an empty string if it shouldn't be shown
+func argumentLabel(argType ArgumentType) string {
+	definition, ok := lookupArgumentType(argType)
+	if !ok {
+		return string(argType)
+	}
+	return definition.Label
+}
+
+// valueLabel returns the label of the argument type with any limits on the value, i.e. "int 1..65535"
+func valueLabel(argType ArgumentType, c valueConstraints) string {
+	label := argumentLabel(argType)
+	if label == "" {
+		return ""
+	}
+	if c.min != nil || c.max != nil {
+		label += " " + rangeLabel(formatLimit(c.min), formatLimit(c.max))
+	}
+	if c.minLength > 0 || c.maxLength > 0 {
+		label += " len " + rangeLabel(formatLength(c.minLength), formatLength(c.maxLength))
+	}
+	return label
+}
+
+// rangeLabel returns the range between the limits, where an empty limit is unbounded
+func rangeLabel(min string, max string) string {
+	switch {
+	case max == "":
+		return ">=" + min
+	case min == "":
+		return "<=" + max
+	default:
+		return min + ".." + max
+	}
+}
+
+func formatLimit(limit *float64) string {
+	if limit == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*limit, 'g', -1, 64)
+}
+
+func formatLength(length int) string {
+	if length <= 0 {
+		return ""
+	}
+	return strconv.Itoa(length)
+}
+
+// isPathArgument returns true if values of the argument type are file or directory paths
+func isPathArgument(argType ArgumentType) bool {
+	return argType == FileArgument || argType == DirArgument || argType == FileDirArgument
+}
+
+// validateArgumentType returns an error if the argument type isn't registered
+func validateArgumentType(argType ArgumentType) error {
+	if _, ok := lookupArgumentType(argType); !ok {
+		return errors.New("unknown argument type: " + string(argType))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package bubblecomplete
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRegisterArgumentType(t *testing.T) {
+	versions := []string{"1.0.0", "1.1.0", "2.0.0"}
+	err := RegisterArgumentType("semver", ArgumentTypeDefinition{
+		Label: "version",
+		Validate: func(ctx ValueContext, value string) error {
+			if strings.Count(value, ".") != 2 {
+				return errors.New("invalid version for argument: " + ctx.Name)
+			}
+			return nil
+		},
+		Complete: func(ctx ValueContext, partial string) []Suggestion {
+			suggestions := []Suggestion{}
+			for _, version := range versions {
+				if strings.HasPrefix(version, partial) {
+					suggestions = append(suggestions, Suggestion{Value: version})
+				}
+			}
+			return suggestions
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterArgumentType() == %v, expected nil", err)
+	}
+	t.Cleanup(func() {
+		argumentTypesMu.Lock()
+		defer argumentTypesMu.Unlock()
+		delete(argumentTypes, "semver")
+	})
+
+	release := &Command{
+		Command: "release",
+		PositionalArguments: []*PositionalArgument{
+			{Name: "version", Description: "Version to release", Type: "semver", Required: true},
+		},
+	}
+	if err := release.Validate(); err != nil {
+		t.Fatalf("Validate() == %v, expected nil", err)
+	}
+	commands := []*Command{release}
+
+	if err := validateCommandInput(parseInput("release 1.1.0", commands)); err != nil {
+		t.Errorf("expected 1.1.0 to be valid, got %v", err)
+	}
+	err = validateCommandInput(parseInput("release 1.1", commands))
+	if err == nil || err.Error() != "invalid version for argument: version" {
+		t.Errorf("validateCommandInput() == %v, expected invalid version", err)
+	}
+
+	names := completionNames(getCompletions(parseInput("release 1.", commands)))
+	if !slices.Equal(names, []string{"1.0.0", "1.1.0"}) {
+		t.Errorf("getCompletions() == %v, expected [1.0.0 1.1.0]", names)
+	}
+
+	description := release.PositionalArguments[0].getDescription()
+	if description != "Version to release [version] [required]" {
+		t.Errorf("getDescription() == %q, expected the type label", description)
+	}
+}
+
+func TestRegisterArgumentTypeErrors(t *testing.T) {
+	validate := func(ctx ValueContext, value string) error { return nil }
+
+	if err := RegisterArgumentType(IntArgument, ArgumentTypeDefinition{Validate: validate}); err == nil {
+		t.Errorf("expected an error registering a built-in type again")
+	}
+	if err := RegisterArgumentType("", ArgumentTypeDefinition{Validate: validate}); err == nil {
+		t.Errorf("expected an error registering a type without a name")
+	}
+	if err := RegisterArgumentType("novalidator", ArgumentTypeDefinition{}); err == nil {
+		t.Errorf("expected an error registering a type without a validator")
+	}
+
+	flag := &Flag{LongFlag: "--level", Type: "unregistered"}
+	if err := flag.Validate(); err == nil || err.Error() != "unknown argument type: unregistered" {
+		t.Errorf("Validate() == %v, expected unknown argument type", err)
+	}
+}
+
+func TestValueLabel(t *testing.T) {
+	low, high := 1.0, 65535.0
+	cases := []struct {
+		flag     Flag
+		expected string
+	}{
+		{Flag{Description: "Port", Type: IntArgument, Min: &low, Max: &high}, "Port [int 1..65535]"},
+		{Flag{Description: "Port", Type: IntArgument, Min: &low}, "Port [int >=1]"},
+		{Flag{Description: "Ratio", Type: FloatArgument, Max: &high}, "Ratio [float <=65535]"},
+		{Flag{Description: "Name", Type: StringArgument, MinLength: 3, MaxLength: 20}, "Name [string len 3..20]"},
+		{Flag{Description: "Verbose", Type: BoolArgument}, "Verbose"},
+	}
+
+	for _, c := range cases {
+		if description := c.flag.getDescription(); description != c.expected {
+			t.Errorf("getDescription() == %q, expected %q", description, c.expected)
+		}
+	}
+}
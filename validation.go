@@ -1,11 +1,22 @@
 package bubblecomplete
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
+	"io/fs"
+	"math"
+	"net/netip"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 func (m *Model) validateInput() error {
@@ -13,186 +24,182 @@ func (m *Model) validateInput() error {
 		return nil
 	}
 
-	err := validateCommandInput(m.input.Value(), m.Commands)
+	err := validateCommandInput(m.parsed)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func validateCommandInput(input string, commands []*Command) error {
-	parts := splitInput(input)
-	if len(parts) == 0 {
+func validateCommandInput(p *parsedInput) error {
+	if len(p.tokens) == 0 {
 		return errors.New("empty command")
 	}
+	if p.err != nil {
+		return p.err
+	}
 
-	var parentCmd *Command
-	var globalFlags []*Flag
-	currentCommands := commands
-	positionalIndex := 0
-	isCommand := true
-
-	for i := 0; i < len(parts); i++ {
-		part := parts[i]
-
-		if isCommand {
-			cmd, err := findCommand(currentCommands, part)
-			if err != nil {
-				if parentCmd == nil {
-					return errors.New("invalid command: " + part)
-				}
-				// If no subcommand is found, stop looking for commands
-				isCommand = false
-				i-- // Reprocess the current part as a flag or positional argument
-				continue
-			}
-			parentCmd = cmd
-			currentCommands = cmd.SubCommands
-			isCommand = len(cmd.SubCommands) > 0
-			for _, flag := range cmd.Flags {
-				if flag.Persistent {
-					globalFlags = append(globalFlags, flag)
-				}
-			}
-			continue
-		}
-
-		if strings.HasPrefix(part, "--") {
-			err := validateLongFlag(part, parts, &i, parentCmd, globalFlags)
-			if err != nil {
-				return err
-			}
-			continue
+	parsed := newParsedCommand(p)
+	for i, flag := range p.flags {
+		if !flag.flag.repeatable() && p.enteredFlag(flag.flag, i) {
+			return fmt.Errorf("flag '%s' can only be entered once", flag.name)
 		}
-
-		if strings.HasPrefix(part, "-") && !strings.HasPrefix(part, "--") {
-			err := validateShortFlags(part, parts, &i, parentCmd, globalFlags)
-			if err != nil {
-				return err
-			}
-			continue
+		if err := validateFlag(p, parsed, flag); err != nil {
+			return err
 		}
+	}
 
-		if positionalIndex < len(parentCmd.PositionalArguments) {
-			err := validatePositionalArgument(part, &positionalIndex, parentCmd)
-			if err != nil {
-				return err
-			}
-			continue
+	for _, positional := range p.positionals {
+		if err := validatePositionalArgument(p, parsed, positional); err != nil {
+			return err
 		}
-
-		return errors.New("unexpected argument: " + part)
 	}
 
-	// Check if all required positional arguments are present
-	expectedPositionalArgs := 0
-	for _, cmd := range parentCmd.PositionalArguments {
-		if cmd.Required {
-			expectedPositionalArgs++
-		}
+	// Check if all required positional arguments are present, with enough values for a variadic one
+	entered := map[*PositionalArgument]int{}
+	for _, positional := range p.positionals {
+		entered[positional.arg]++
 	}
-	if positionalIndex < expectedPositionalArgs {
-		return fmt.Errorf("missing positional argument: %s", parentCmd.PositionalArguments[positionalIndex].Name)
+	for _, arg := range p.command().PositionalArguments {
+		count, expected := entered[arg], arg.minValues()
+		if count == 0 && expected > 0 {
+			return fmt.Errorf("missing positional argument: %s", arg.Name)
+		}
+		if count < expected {
+			return fmt.Errorf("expected at least %d values for argument: %s", expected, arg.Name)
+		}
 	}
 
-	return nil
+	if err := validateRequiredFlags(p); err != nil {
+		return err
+	}
+	return validateFlagGroups(p)
 }
 
-func validateLongFlag(part string, parts []string, i *int, parentCmd *Command, globalFlags []*Flag) error {
-	argName := part
-	argValue := ""
-
-	if strings.Contains(part, "=") {
-		argParts := strings.SplitN(part, "=", 2)
-		argName = argParts[0]
-		argValue = argParts[1]
+// validateRequiredFlags returns an error if a required flag is missing, or a flag was entered
+// without the flags it requires
+func validateRequiredFlags(p *parsedInput) error {
+	for _, flag := range p.availableFlags(len(p.commands)) {
+		if flag.Required && !p.enteredFlag(flag, len(p.flags)) {
+			return fmt.Errorf("missing required flag: %s", flag.displayName())
+		}
 	}
 
-	if parentCmd == nil {
-		return errors.New("invalid flag: " + part)
+	// Flags can require any flag of the commands entered, as flags of parent commands can be
+	// entered before their subcommands
+	all := []*Flag{}
+	for _, cmd := range p.commands {
+		all = append(all, cmd.Flags...)
 	}
+	for _, parsed := range p.flags {
+		for _, name := range parsed.flag.Requires {
+			required, err := findFlag(all, name)
+			if err != nil || !p.enteredFlag(required, len(p.flags)) {
+				return fmt.Errorf("flag %s requires %s", parsed.name, name)
+			}
+		}
+	}
+	return nil
+}
 
-	allFlags := append(parentCmd.Flags, globalFlags...)
-	arg, err := findFlag(allFlags, argName)
-	if err != nil {
-		return fmt.Errorf("flag '%s' not found", argName)
+// validateFlagGroups returns an error if the flags entered break one of the flag groups of the command
+func validateFlagGroups(p *parsedInput) error {
+	cmd := p.command()
+	if cmd == nil {
+		return nil
 	}
 
-	if arg.getType() != BoolArgument && argValue == "" {
-		if *i == len(parts)-1 || strings.HasPrefix(parts[*i+1], "-") {
-			return fmt.Errorf("missing value for flag '%s'", argName)
+	for _, group := range cmd.FlagGroups {
+		// The flags of the group that were entered, as they were typed
+		entered := []string{}
+		for _, flag := range p.groupFlags(len(p.commands), group) {
+			if parsed := p.findParsedFlag(flag, len(p.flags)); parsed != nil {
+				entered = append(entered, parsed.name)
+			}
 		}
-		argValue = parts[*i+1]
-		*i++
-	}
 
-	err = validateArgumentValue(arg, argValue)
-	if err != nil {
-		return err
+		switch {
+		case (group.Kind == MutuallyExclusive || group.Kind == ExactlyOneOf) && len(entered) > 1:
+			return fmt.Errorf("%s and %s cannot be used together", entered[0], entered[1])
+		case group.Kind == RequiredTogether && len(entered) > 0 && len(entered) < len(group.Flags):
+			return fmt.Errorf("%s must be used together", joinFlagNames(group.Flags, "and"))
+		case group.Kind == AtLeastOneOf && len(entered) == 0:
+			return fmt.Errorf("at least one of %s is required", joinFlagNames(group.Flags, "or"))
+		case group.Kind == ExactlyOneOf && len(entered) == 0:
+			return fmt.Errorf("exactly one of %s is required", joinFlagNames(group.Flags, "or"))
+		}
 	}
 	return nil
 }
 
-func validateShortFlags(part string, parts []string, i *int, parentCmd *Command, globalFlags []*Flag) error {
-	combinedFlags := part[1:]
-
-	if len(combinedFlags) == 0 {
-		return errors.New("invalid argument: " + part)
+// joinFlagNames joins the flag names into a list, i.e. "--json, --yaml or --csv"
+func joinFlagNames(names []string, conjunction string) string {
+	if len(names) == 1 {
+		return names[0]
 	}
+	return strings.Join(names[:len(names)-1], ", ") + " " + conjunction + " " + names[len(names)-1]
+}
 
-	for j := 0; j < len(combinedFlags); j++ {
-		argName := "-" + string(combinedFlags[j])
-		argValue := ""
-
-		if parentCmd == nil {
-			return errors.New("invalid argument: " + part)
+// deprecationWarnings returns a warning for each deprecated command and flag entered
+func deprecationWarnings(p *parsedInput) []string {
+	var warnings []string
+	for _, cmd := range p.commands {
+		if cmd.Deprecated != "" {
+			warnings = append(warnings, fmt.Sprintf("command '%s' is deprecated: %s", cmd.Command, cmd.Deprecated))
 		}
-
-		allFlags := append(parentCmd.Flags, globalFlags...)
-		arg, err := findFlag(allFlags, argName)
-		if err != nil {
-			return fmt.Errorf("flag '%s' not found", argName)
+	}
+	for _, flag := range p.flags {
+		if flag.flag.Deprecated != "" {
+			warnings = append(warnings, fmt.Sprintf("flag '%s' is deprecated: %s", flag.name, flag.flag.Deprecated))
 		}
+	}
+	return warnings
+}
 
-		if arg.getType() != BoolArgument {
-			if j == len(combinedFlags)-1 {
-				if *i == len(parts)-1 || strings.HasPrefix(parts[*i+1], "-") {
-					return fmt.Errorf("missing value for flag '%s'", argName)
-				}
-				argValue = parts[*i+1]
-				*i++
-			} else {
-				return fmt.Errorf("flag '%s' must be the last in a combined group", argName)
-			}
+func validateFlag(p *parsedInput, parsed *ParsedCommand, flag *parsedFlag) error {
+	if flag.flag.Count && flag.hasValue {
+		return fmt.Errorf("count flag '%s' doesn't take a value", flag.name)
+	}
+	if flag.valueToken != -1 {
+		if err := checkUnclosedQuote(flag.flag, p.tokens[flag.valueToken]); err != nil {
+			return err
 		}
-
-		err = validateArgumentValue(arg, argValue)
-		if err != nil {
+	} else if flag.hasValue {
+		if err := checkUnclosedQuote(flag.flag, p.tokens[flag.token]); err != nil {
 			return err
 		}
 	}
+
+	// Bool flags entered without a value are true, the same as in the parsed command
+	value := flag.value
+	if flag.flag.Type == BoolArgument && !flag.hasValue {
+		value = "true"
+	}
+	err := validateArgumentValue(newValueContext(p, flag.flag, parsed), value)
+	if err != nil {
+		return err
+	}
 	return nil
 }
 
-func validatePositionalArgument(part string, positionalIndex *int, parentCmd *Command) error {
-	positionalArg := parentCmd.PositionalArguments[*positionalIndex]
-	if positionalArg == nil {
-		return errors.New("unexpected argument: " + part)
-	}
-	if !positionalArg.Required && part == "" {
+func validatePositionalArgument(p *parsedInput, parsed *ParsedCommand, positional *parsedPositional) error {
+	token := p.tokens[positional.token]
+	if !positional.arg.Required && token.Value == "" {
 		return nil
 	}
-	err := validateArgumentValue(positionalArg, part)
+	if err := checkUnclosedQuote(positional.arg, token); err != nil {
+		return err
+	}
+	err := validateArgumentValue(newValueContext(p, positional.arg, parsed), token.Value)
 	if err != nil {
 		return err
 	}
-	*positionalIndex++
 	return nil
 }
 
 func findCommand(commands []*Command, name string) (*Command, error) {
 	for _, cmd := range commands {
-		if cmd.Command == name {
+		if cmd.hasName(name) {
 			return cmd, nil
 		}
 	}
@@ -208,120 +215,479 @@ func findFlag(arguments []*Flag, name string) (*Flag, error) {
 	return nil, errors.New("argument not found")
 }
 
-func validateArgumentValue(arg Argument, value string) error {
-	switch arg.getType() {
-	case StringArgument:
-		return validateStringArgument(arg, value)
-	case IntArgument:
-		return validateIntArgument(arg, value)
-	case FloatArgument:
-		return validateFloatArgument(arg, value)
-	case BoolArgument:
-		// No validation needed for boolean, presence is enough
-		return nil
-	case FileArgument:
-		return validateFileArgument(arg, value)
-	case DirArgument:
-		return validateDirArgument(arg, value)
-	case FileDirArgument:
-		return validateFileDirArgument(arg, value)
-	default:
-		return errors.New("unknown argument type: " + string(arg.getType()))
+// validateArgumentValue validates the value with the validator of the argument type
+func validateArgumentValue(ctx ValueContext, value string) error {
+	definition, ok := lookupArgumentType(ctx.Argument.getType())
+	if !ok {
+		return errors.New("unknown argument type: " + string(ctx.Argument.getType()))
+	}
+	if err := definition.Validate(ctx, value); err != nil {
+		return err
 	}
+	return validateConstraints(ctx, value)
 }
 
-func validateStringArgument(arg Argument, value string) error {
-	if err := checkEmptyString(arg, value); err != nil {
-		return err
+// validateConstraints checks the value is within the limits set on the flag or positional argument
+func validateConstraints(ctx ValueContext, value string) error {
+	c := ctx.Argument.getConstraints()
+
+	if c.min != nil || c.max != nil {
+		// The type validator has already checked the value is a number
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return errors.New("invalid number value for argument: " + ctx.Name)
+		}
+		if (c.min != nil && number < *c.min) || (c.max != nil && number > *c.max) {
+			return fmt.Errorf("value %s for argument: %s is out of range (%s)", value, ctx.Name, rangeLabel(formatLimit(c.min), formatLimit(c.max)))
+		}
 	}
-	if err := checkUnclosedQuote(arg, value, "\""); err != nil {
-		return err
+
+	if c.minLength > 0 || c.maxLength > 0 {
+		length := utf8.RuneCountInString(value)
+		if (c.minLength > 0 && length < c.minLength) || (c.maxLength > 0 && length > c.maxLength) {
+			return fmt.Errorf("value for argument: %s must be %s characters long", ctx.Name, rangeLabel(formatLength(c.minLength), formatLength(c.maxLength)))
+		}
+	}
+
+	if c.pattern != nil && !matchesPattern(c.pattern, value) {
+		return fmt.Errorf("invalid value '%s' for argument: %s (must match %s)", value, ctx.Name, c.pattern)
 	}
-	if err := checkUnclosedQuote(arg, value, "'"); err != nil {
+
+	return nil
+}
+
+// matchesPattern returns true if the whole value matches the pattern, as if it were anchored with ^ and $
+func matchesPattern(pattern *regexp.Regexp, value string) bool {
+	anchored, err := regexp.Compile(`^(?:` + pattern.String() + `)$`)
+	if err != nil {
+		return pattern.MatchString(value)
+	}
+	return anchored.MatchString(value)
+}
+
+// validateConstraintDefinition returns an error if the limits set on a flag or positional argument can't be met
+func validateConstraintDefinition(argType ArgumentType, c valueConstraints) error {
+	if (c.min != nil || c.max != nil) && argType != IntArgument && argType != FloatArgument {
+		return errors.New("min and max can only be set on int and float arguments")
+	}
+	if c.min != nil && c.max != nil && *c.min > *c.max {
+		return errors.New("min must not be greater than max")
+	}
+	if c.minLength < 0 || c.maxLength < 0 {
+		return errors.New("min and max length must not be negative")
+	}
+	if c.maxLength > 0 && c.minLength > c.maxLength {
+		return errors.New("min length must not be greater than max length")
+	}
+	return nil
+}
+
+func newValueContext(p *parsedInput, arg Argument, parsed *ParsedCommand) ValueContext {
+	return ValueContext{Argument: arg, Name: arg.getName(), Parsed: parsed, FS: p.fsys, WorkingDir: p.workingDir}
+}
+
+func validateStringArgument(ctx ValueContext, value string) error {
+	if err := checkEmptyString(ctx, value); err != nil {
 		return err
 	}
 	return nil
 }
 
-func checkEmptyString(arg Argument, value string) error {
+func checkEmptyString(ctx ValueContext, value string) error {
 	if value == "" {
-		return errors.New("missing value for argument: " + arg.getName())
+		return errors.New("missing value for argument: " + ctx.Name)
 	}
 	return nil
 }
 
-func checkUnclosedQuote(arg Argument, value, quote string) error {
-	if len(value) == 1 && value == quote {
-		return errors.New("missing closing quote")
-	}
-	if len(value) > 1 && strings.HasPrefix(value, quote) && !strings.HasSuffix(value, quote) {
+func checkUnclosedQuote(arg Argument, token Token) error {
+	if token.Unclosed && token.Quoted() {
 		return errors.New("missing closing quote for argument: " + arg.getName())
 	}
 	return nil
 }
 
-func validateIntArgument(arg Argument, value string) error {
+func validateIntArgument(ctx ValueContext, value string) error {
 	if _, err := strconv.Atoi(value); err != nil {
-		return errors.New("invalid integer value for argument: " + arg.getName())
+		return errors.New("invalid integer value for argument: " + ctx.Name)
+	}
+	return nil
+}
+
+func validateBoolArgument(ctx ValueContext, value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return errors.New("invalid boolean value for argument: " + ctx.Name)
 	}
 	return nil
 }
 
-func validateFloatArgument(arg Argument, value string) error {
+func validateFloatArgument(ctx ValueContext, value string) error {
 	if _, err := strconv.ParseFloat(value, 64); err != nil {
-		return errors.New("invalid float value for argument: " + arg.getName())
+		return errors.New("invalid float value for argument: " + ctx.Name)
 	}
 	return nil
 }
 
-func validateFileArgument(arg Argument, value string) error {
-	value = removeQuotes(value)
-	file, err := os.Stat(value)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return errors.New("file does not exist for argument: " + arg.getName())
+func validateChoiceArgument(ctx ValueContext, value string) error {
+	choices := make([]string, 0, len(ctx.Argument.getChoices()))
+	for _, choice := range ctx.Argument.getChoices() {
+		if choice.Value == value {
+			return nil
 		}
-		return errors.New("error accessing file for argument: " + arg.getName())
+		choices = append(choices, choice.Value)
+	}
+	return fmt.Errorf("invalid value '%s' for argument: %s (valid choices: %s)", value, ctx.Name, strings.Join(choices, ", "))
+}
+
+func validateDurationArgument(ctx ValueContext, value string) error {
+	if err := checkEmptyString(ctx, value); err != nil {
+		return err
+	}
+	if _, err := parseDuration(value); err != nil {
+		return invalidValueError("duration", ctx.Name, err)
+	}
+	return nil
+}
+
+func validateURLArgument(ctx ValueContext, value string) error {
+	if err := checkEmptyString(ctx, value); err != nil {
+		return err
+	}
+	if _, err := parseURL(value); err != nil {
+		return invalidValueError("URL", ctx.Name, err)
+	}
+	return nil
+}
+
+func validateIPArgument(ctx ValueContext, value string) error {
+	if err := checkEmptyString(ctx, value); err != nil {
+		return err
+	}
+	if _, err := parseIP(value); err != nil {
+		return invalidValueError("IP address", ctx.Name, err)
+	}
+	return nil
+}
+
+func validateCIDRArgument(ctx ValueContext, value string) error {
+	if err := checkEmptyString(ctx, value); err != nil {
+		return err
+	}
+	if _, err := parseCIDR(value); err != nil {
+		return invalidValueError("CIDR", ctx.Name, err)
+	}
+	return nil
+}
+
+func validateTimeArgument(ctx ValueContext, value string) error {
+	if err := checkEmptyString(ctx, value); err != nil {
+		return err
+	}
+	if _, err := parseTime(value, ctx.Argument.getLayout()); err != nil {
+		return invalidValueError("time", ctx.Name, err)
+	}
+	return nil
+}
+
+func validateByteSizeArgument(ctx ValueContext, value string) error {
+	if err := checkEmptyString(ctx, value); err != nil {
+		return err
 	}
-	if file.IsDir() {
-		return errors.New("file path is a directory: " + arg.getName())
+	if _, err := parseByteSize(value); err != nil {
+		return invalidValueError("byte size", ctx.Name, err)
 	}
 	return nil
 }
 
-func validateDirArgument(arg Argument, value string) error {
-	value = removeQuotes(value)
-	file, err := os.Stat(value)
+func validateRegexArgument(ctx ValueContext, value string) error {
+	if _, err := parseRegex(value); err != nil {
+		return invalidValueError("regular expression", ctx.Name, err)
+	}
+	return nil
+}
+
+func validateJSONArgument(ctx ValueContext, value string) error {
+	if err := checkEmptyString(ctx, value); err != nil {
+		return err
+	}
+	if err := parseJSON(value, nil); err != nil {
+		return invalidValueError("JSON", ctx.Name, err)
+	}
+	return nil
+}
+
+// invalidValueError returns the error for an invalid value, with the reason it's invalid
+func invalidValueError(kind string, name string, reason error) error {
+	return fmt.Errorf("invalid %s value for argument: %s (%v)", kind, name, reason)
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.New("expected a number with a unit, i.e. 30s or 1h30m")
+	}
+	return d, nil
+}
+
+func parseURL(value string) (*url.URL, error) {
+	u, err := url.Parse(value)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return errors.New("directory does not exist for argument: " + arg.getName())
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return nil, urlErr.Err
 		}
-		return errors.New("error accessing directory for argument: " + arg.getName())
+		return nil, err
 	}
-	if !file.IsDir() {
-		return errors.New("directory path is a file: " + arg.getName())
+	if u.Scheme == "" {
+		return nil, errors.New("missing scheme, i.e. https://")
 	}
-	return nil
+	if u.Host == "" {
+		return nil, errors.New("missing host")
+	}
+	return u, nil
 }
 
-func validateFileDirArgument(arg Argument, value string) error {
-	// If value is wrapped in quotes, remove them
-	value = removeQuotes(value)
-	_, err := os.Stat(value)
+func parseIP(value string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(value)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return errors.New("file or directory does not exist for argument: " + arg.getName())
+		return netip.Addr{}, errors.New("expected an IPv4 or IPv6 address, i.e. 192.168.0.1")
+	}
+	return addr, nil
+}
+
+func parseCIDR(value string) (netip.Prefix, error) {
+	if !strings.Contains(value, "/") {
+		return netip.Prefix{}, errors.New("missing prefix length, i.e. 10.0.0.0/8")
+	}
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return netip.Prefix{}, errors.New("expected an address and prefix length, i.e. 10.0.0.0/8")
+	}
+	return prefix, nil
+}
+
+// parseTime parses the value with the layout, or RFC 3339 if the layout is empty
+func parseTime(value string, layout string) (time.Time, error) {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected the layout %s", layout)
+	}
+	return t, nil
+}
+
+// byteSizeUnits are the multipliers of the byte size units, SI units are powers of 1000 and binary units powers of 1024
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"k":   1000,
+	"kb":  1000,
+	"m":   1000 * 1000,
+	"mb":  1000 * 1000,
+	"g":   1000 * 1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"t":   1000 * 1000 * 1000 * 1000,
+	"tb":  1000 * 1000 * 1000 * 1000,
+	"p":   1000 * 1000 * 1000 * 1000 * 1000,
+	"pb":  1000 * 1000 * 1000 * 1000 * 1000,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+	"pi":  1 << 50,
+	"pib": 1 << 50,
+}
+
+// parseByteSize parses a size such as `512`, `1.5GB` or `10MiB` into a number of bytes
+func parseByteSize(value string) (int64, error) {
+	number := strings.TrimRightFunc(value, unicode.IsLetter)
+	unit := strings.TrimSpace(value[len(number):])
+	number = strings.TrimSpace(number)
+
+	multiplier, ok := byteSizeUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit '%s', expected i.e. KB, MiB or GB", unit)
+	}
+
+	if i, err := strconv.ParseInt(number, 10, 64); err == nil {
+		if i < 0 {
+			return 0, errors.New("size can't be negative")
 		}
-		return errors.New("error accessing file or directory for argument: " + arg.getName())
+		if i > math.MaxInt64/multiplier {
+			return 0, errors.New("size is too large")
+		}
+		return i * multiplier, nil
+	}
+
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errors.New("expected a number with an optional unit, i.e. 512KB or 10MiB")
+	}
+	if f < 0 {
+		return 0, errors.New("size can't be negative")
+	}
+	size := f * float64(multiplier)
+	if size >= math.MaxInt64 {
+		return 0, errors.New("size is too large")
+	}
+	return int64(size), nil
+}
+
+func parseRegex(value string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(value)
+	if err != nil {
+		var syntaxErr *syntax.Error
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%s: `%s`", syntaxErr.Code, syntaxErr.Expr)
+		}
+		return nil, err
+	}
+	return re, nil
+}
+
+// parseJSON unmarshals the value into v, or only checks that it's valid JSON if v is nil
+func parseJSON(value string, v any) error {
+	if v == nil {
+		var discard any
+		v = &discard
+	}
+	err := json.Unmarshal([]byte(value), v)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Errorf("%v at offset %d", syntaxErr, syntaxErr.Offset)
+	}
+	return err
+}
+
+func validateFileArgument(ctx ValueContext, value string) error {
+	return validatePathArgument(ctx, value)
+}
+
+func validateDirArgument(ctx ValueContext, value string) error {
+	return validatePathArgument(ctx, value)
+}
+
+func validateFileDirArgument(ctx ValueContext, value string) error {
+	return validatePathArgument(ctx, value)
+}
+
+// validatePathArgument checks the path is of the argument type and meets its path options
+func validatePathArgument(ctx ValueContext, value string) error {
+	argType := ctx.Argument.getType()
+	kind := pathKind(argType)
+	options := ctx.Argument.getPathOptions()
+
+	info, err := statPath(ctx, value)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			return errors.New("error accessing " + kind + " for argument: " + ctx.Name)
+		}
+		if options.existence == PathMustExist {
+			return errors.New(kind + " does not exist for argument: " + ctx.Name)
+		}
+		if argType == FileArgument {
+			if err := checkFileName(ctx, value); err != nil {
+				return err
+			}
+		}
+		return checkParentDir(ctx, value)
+	}
+
+	if options.existence == PathMustNotExist {
+		return errors.New(kind + " already exists for argument: " + ctx.Name)
+	}
+	if argType == FileArgument && info.IsDir() {
+		return errors.New("file path is a directory: " + ctx.Name)
+	}
+	if argType == DirArgument && !info.IsDir() {
+		return errors.New("directory path is a file: " + ctx.Name)
+	}
+	if !info.IsDir() {
+		if err := checkFileName(ctx, value); err != nil {
+			return err
+		}
+	}
+	if options.writable && !isWritable(ctx, value, info) {
+		return errors.New(kind + " is not writable for argument: " + ctx.Name)
+	}
+	return nil
+}
+
+// checkParentDir checks the parent directory of a path that doesn't exist yet exists, and is writable if required
+func checkParentDir(ctx ValueContext, value string) error {
+	parent := parentPath(ctx, value)
+	info, err := statPath(ctx, parent)
+	if err != nil || !info.IsDir() {
+		return errors.New("parent directory does not exist for argument: " + ctx.Name)
+	}
+	if ctx.Argument.getPathOptions().writable && !isWritable(ctx, parent, info) {
+		return errors.New("parent directory is not writable for argument: " + ctx.Name)
 	}
 	return nil
 }
 
-func removeQuotes(s string) string {
-	if len(s) >= 2 {
-		if (s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'') {
-			s = s[1 : len(s)-1]
+// checkFileName checks the file name has one of the allowed extensions or matches one of the allowed globs
+func checkFileName(ctx ValueContext, value string) error {
+	options := ctx.Argument.getPathOptions()
+	name := path.Base(filepath.ToSlash(value))
+	if matchesFileName(name, options) {
+		return nil
+	}
+	allowed := append(append([]string{}, options.extensions...), options.globs...)
+	return fmt.Errorf("invalid file name '%s' for argument: %s (expected %s)", name, ctx.Name, strings.Join(allowed, ", "))
+}
+
+// matchesFileName returns true if there are no allowed extensions or globs, or the name matches one of them
+func matchesFileName(name string, options pathOptions) bool {
+	if len(options.extensions) == 0 && len(options.globs) == 0 {
+		return true
+	}
+	for _, extension := range options.extensions {
+		if !strings.HasPrefix(extension, ".") {
+			extension = "." + extension
+		}
+		if strings.EqualFold(path.Ext(name), extension) {
+			return true
+		}
+	}
+	for _, glob := range options.globs {
+		if matched, _ := path.Match(glob, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// pathKind returns the name of the kind of path the argument type takes, for use in error messages
+func pathKind(argType ArgumentType) string {
+	switch argType {
+	case DirArgument:
+		return "directory"
+	case FileDirArgument:
+		return "file or directory"
+	default:
+		return "file"
+	}
+}
+
+// validatePathOptionsDefinition returns an error if the path options are set on an argument type they can't be used with
+func validatePathOptionsDefinition(argType ArgumentType, options pathOptions) error {
+	if (len(options.extensions) > 0 || len(options.globs) > 0) && argType != FileArgument && argType != FileDirArgument {
+		return errors.New("extensions and globs can only be set on file arguments")
+	}
+	if (options.existence != PathMustExist || options.writable) && !isPathArgument(argType) {
+		return errors.New("existence and writable can only be set on file and directory arguments")
+	}
+	for _, glob := range options.globs {
+		if _, err := path.Match(glob, ""); err != nil {
+			return errors.New("invalid glob pattern: " + glob)
 		}
 	}
-	return s
+	return nil
 }
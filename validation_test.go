@@ -1,40 +1,536 @@
 package bubblecomplete
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
 
-func TestRemoveQuotes(t *testing.T) {
-	input := "\"This is a test\""
-	expected := "This is a test"
-	result := removeQuotes(input)
-	if result != expected {
-		t.Errorf("removeQuotes(%q) == %q, expected %q", input, result, expected)
+func TestValidateCommandInputQuotes(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "commit",
+			Flags: []*Flag{
+				{ShortFlag: "-m", LongFlag: "--message", Type: StringArgument},
+			},
+		},
 	}
 
-	input = "'This is a test'"
-	expected = "This is a test"
-	result = removeQuotes(input)
-	if result != expected {
-		t.Errorf("removeQuotes(%q) == %q, expected %q", input, result, expected)
+	cases := []struct {
+		input string
+		valid bool
+	}{
+		{"commit -m \"hello world\"", true},
+		{"commit -m 'hello world'", true},
+		{"commit --message=\"hello world\"", true},
+		{"commit -m \"hello world", false},
+		{"commit --message='hello", false},
+		{"commit -m", false},
 	}
 
-	input = "\"This is a test"
-	expected = "\"This is a test"
-	result = removeQuotes(input)
-	if result != expected {
-		t.Errorf("removeQuotes(%q) == %q, expected %q", input, result, expected)
+	for _, c := range cases {
+		err := validateCommandInput(parseInput(c.input, commands))
+		if (err == nil) != c.valid {
+			t.Errorf("validateCommandInput(%q) == %v, expected valid %t", c.input, err, c.valid)
+		}
+	}
+}
+
+func TestValidateCommandInputEscapedPath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "My Documents")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	commands := []*Command{
+		{
+			Command: "cat",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileArgument, Required: true},
+			},
+		},
+	}
+
+	escapedDir := filepath.Join(filepath.Dir(dir), "My\\ Documents")
+	inputs := []string{
+		"cat " + filepath.Join(escapedDir, "file.txt"),
+		"cat \"" + file + "\"",
+		"cat '" + file + "'",
+	}
+	for _, input := range inputs {
+		if err := validateCommandInput(parseInput(input, commands)); err != nil {
+			t.Errorf("validateCommandInput(%q) == %v, expected nil", input, err)
+		}
+	}
+}
+
+func TestValidateCommandInput(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"git stash pop", ""},
+		{"git --help stash pop", ""},
+		{"git stash --help pop", ""},
+		{"git commit -am \"message\"", ""},
+		{"git commit -ma \"message\"", "flag '-m' must be the last in a combined group"},
+		{"git commit -x", "flag '-x' not found"},
+		{"git commit --message", "missing value for flag '--message'"},
+		{"git commit -m --amend", "missing value for flag '-m'"},
+		{"git push origin main extra", "unexpected argument: extra"},
+		{"git commit --amend=false", ""},
+		{"git commit --amend=banana", "invalid boolean value for argument: --amend"},
+		{"git commit --amend=", "invalid boolean value for argument: --amend"},
+		{"nope", "invalid command: nope"},
+		{"-", "invalid flag: -"},
+	}
+
+	for _, c := range cases {
+		err := validateCommandInput(parseInput(c.input, testCommands()))
+		result := ""
+		if err != nil {
+			result = err.Error()
+		}
+		if result != c.expected {
+			t.Errorf("validateCommandInput(%q) == %q, expected %q", c.input, result, c.expected)
+		}
+	}
+}
+
+func TestValidateCommandInputRepeatedFlags(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"git commit -m a -m b", "flag '-m' can only be entered once"},
+		{"git commit --all --all", "flag '--all' can only be entered once"},
+		{"git commit -a --all", "flag '--all' can only be entered once"},
+		{"git commit -aa", "flag '-a' can only be entered once"},
+		{"git commit -a -m message", ""},
+	}
+
+	for _, c := range cases {
+		err := validateCommandInput(parseInput(c.input, testCommands()))
+		result := ""
+		if err != nil {
+			result = err.Error()
+		}
+		if result != c.expected {
+			t.Errorf("validateCommandInput(%q) == %q, expected %q", c.input, result, c.expected)
+		}
+	}
+}
+
+func TestValidateChoiceArgument(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "export",
+			Flags: []*Flag{
+				{LongFlag: "--format", Type: ChoiceArgument, Choices: []Choice{{Value: "json"}, {Value: "yaml"}}},
+			},
+		},
+	}
+
+	if err := validateCommandInput(parseInput("export --format=yaml", commands)); err != nil {
+		t.Errorf("expected yaml to be valid, got %v", err)
+	}
+
+	err := validateCommandInput(parseInput("export --format xml", commands))
+	expected := "invalid value 'xml' for argument: --format (valid choices: json, yaml)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("validateCommandInput() == %v, expected %q", err, expected)
+	}
+}
+
+func TestValidateRichArguments(t *testing.T) {
+	commands := []*Command{
+		{
+			Command: "deploy",
+			Flags: []*Flag{
+				{LongFlag: "--timeout", Type: DurationArgument},
+				{LongFlag: "--endpoint", Type: URLArgument},
+				{LongFlag: "--ip", Type: IPArgument},
+				{LongFlag: "--subnet", Type: CIDRArgument},
+				{LongFlag: "--at", Type: TimeArgument},
+				{LongFlag: "--date", Type: TimeArgument, Layout: "2006-01-02"},
+				{LongFlag: "--size", Type: ByteSizeArgument},
+				{LongFlag: "--match", Type: RegexArgument},
+				{LongFlag: "--spec", Type: JSONArgument},
+			},
+		},
+	}
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"deploy --timeout 1h30m", ""},
+		{"deploy --timeout 30", "invalid duration value for argument: --timeout (expected a number with a unit, i.e. 30s or 1h30m)"},
+		{"deploy --endpoint https://example.com/api", ""},
+		{"deploy --endpoint example.com", "invalid URL value for argument: --endpoint (missing scheme, i.e. https://)"},
+		{"deploy --endpoint file:///tmp", "invalid URL value for argument: --endpoint (missing host)"},
+		{"deploy --ip ::1", ""},
+		{"deploy --ip 10.0.0", "invalid IP address value for argument: --ip (expected an IPv4 or IPv6 address, i.e. 192.168.0.1)"},
+		{"deploy --subnet 10.0.0.0/8", ""},
+		{"deploy --subnet 10.0.0.0", "invalid CIDR value for argument: --subnet (missing prefix length, i.e. 10.0.0.0/8)"},
+		{"deploy --subnet 10.0.0.0/40", "invalid CIDR value for argument: --subnet (expected an address and prefix length, i.e. 10.0.0.0/8)"},
+		{"deploy --at 2024-05-01T12:00:00Z", ""},
+		{"deploy --at 2024-05-01", "invalid time value for argument: --at (expected the layout 2006-01-02T15:04:05Z07:00)"},
+		{"deploy --date 2024-05-01", ""},
+		{"deploy --size 10MiB", ""},
+		{"deploy --size 1.5gb", ""},
+		{"deploy --size 10XB", "invalid byte size value for argument: --size (unknown unit 'XB', expected i.e. KB, MiB or GB)"},
+		{"deploy --size MiB", "invalid byte size value for argument: --size (expected a number with an optional unit, i.e. 512KB or 10MiB)"},
+		{"deploy --match '^v[0-9]+$'", ""},
+		{"deploy --match '(a'", "invalid regular expression value for argument: --match (missing closing ): `(a`)"},
+		{"deploy --spec '{\"replicas\": 3}'", ""},
+		{"deploy --spec '{\"replicas\": }'", "invalid JSON value for argument: --spec (invalid character '}' looking for beginning of value at offset 14)"},
+		{"deploy --timeout=", "missing value for argument: --timeout"},
+	}
+
+	for _, c := range cases {
+		err := validateCommandInput(parseInput(c.input, commands))
+		result := ""
+		if err != nil {
+			result = err.Error()
+		}
+		if result != c.expected {
+			t.Errorf("validateCommandInput(%q) == %q, expected %q", c.input, result, c.expected)
+		}
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected int64
+	}{
+		{"512", 512},
+		{"512B", 512},
+		{"1KB", 1000},
+		{"1k", 1000},
+		{"10MiB", 10 << 20},
+		{"1.5GB", 1500000000},
+		{"2TiB", 2 << 40},
+	}
+
+	for _, c := range cases {
+		size, err := parseByteSize(c.input)
+		if err != nil || size != c.expected {
+			t.Errorf("parseByteSize(%q) == %d, %v, expected %d", c.input, size, err, c.expected)
+		}
+	}
+
+	if _, err := parseByteSize("9999999PiB"); err == nil {
+		t.Errorf("parseByteSize() expected an error for a size that's too large")
+	}
+}
+
+func TestValidateConstraints(t *testing.T) {
+	limit := func(f float64) *float64 { return &f }
+	commands := []*Command{
+		{
+			Command: "serve",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "port", Type: IntArgument, Min: limit(1), Max: limit(65535)},
+			},
+			Flags: []*Flag{
+				{LongFlag: "--ratio", Type: FloatArgument, Min: limit(0)},
+				{LongFlag: "--name", Type: StringArgument, MinLength: 3, MaxLength: 8},
+				{LongFlag: "--tag", Type: StringArgument, Pattern: regexp.MustCompile(`^v[0-9]+$`)},
+				{LongFlag: "--env", Type: StringArgument, Pattern: regexp.MustCompile(`[a-z]+|prod-[0-9]+`)},
+			},
+		},
 	}
 
-	input = "'This is a test"
-	expected = "'This is a test"
-	result = removeQuotes(input)
-	if result != expected {
-		t.Errorf("removeQuotes(%q) == %q, expected %q", input, result, expected)
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"serve 8080 --ratio 0.5 --name site --tag v2", ""},
+		{"serve 0", "value 0 for argument: port is out of range (1..65535)"},
+		{"serve 70000", "value 70000 for argument: port is out of range (1..65535)"},
+		{"serve 80 --ratio=-1", "value -1 for argument: --ratio is out of range (>=0)"},
+		{"serve 80 --name ab", "value for argument: --name must be 3..8 characters long"},
+		{"serve 80 --name 'my website'", "value for argument: --name must be 3..8 characters long"},
+		{"serve 80 --tag 2", "invalid value '2' for argument: --tag (must match ^v[0-9]+$)"},
+		{"serve 80 --env dev", ""},
+		{"serve 80 --env prod-2", ""},
+		{"serve 80 --env ABCx", "invalid value 'ABCx' for argument: --env (must match [a-z]+|prod-[0-9]+)"},
+		{"serve 80 --env dev1", "invalid value 'dev1' for argument: --env (must match [a-z]+|prod-[0-9]+)"},
 	}
 
-	input = "'This is a test='"
-	expected = "This is a test="
-	result = removeQuotes(input)
-	if result != expected {
-		t.Errorf("removeQuotes(%q) == %q, expected %q", input, result, expected)
+	for _, c := range cases {
+		err := validateCommandInput(parseInput(c.input, commands))
+		result := ""
+		if err != nil {
+			result = err.Error()
+		}
+		if result != c.expected {
+			t.Errorf("validateCommandInput(%q) == %q, expected %q", c.input, result, c.expected)
+		}
+	}
+
+	invalid := []*Flag{
+		{LongFlag: "--name", Type: StringArgument, Min: limit(1)},
+		{LongFlag: "--port", Type: IntArgument, Min: limit(10), Max: limit(1)},
+		{LongFlag: "--name", Type: StringArgument, MinLength: 5, MaxLength: 2},
+	}
+	for _, flag := range invalid {
+		if err := flag.Validate(); err == nil {
+			t.Errorf("Validate() expected an error for the limits of %+v", flag)
+		}
+	}
+}
+
+func testFlagGroupCommands() []*Command {
+	return []*Command{
+		{
+			Command: "export",
+			Flags: []*Flag{
+				{LongFlag: "--json", Type: BoolArgument},
+				{LongFlag: "--yaml", Type: BoolArgument},
+				{LongFlag: "--csv", Type: BoolArgument},
+				{LongFlag: "--user", Type: StringArgument},
+				{LongFlag: "--password", Type: StringArgument},
+				{LongFlag: "--stdout", Type: BoolArgument},
+				{LongFlag: "--output", Type: StringArgument},
+			},
+			FlagGroups: []FlagGroup{
+				{Kind: MutuallyExclusive, Flags: []string{"--json", "--yaml", "--csv"}},
+				{Kind: RequiredTogether, Flags: []string{"--user", "--password"}},
+				{Kind: ExactlyOneOf, Flags: []string{"--stdout", "--output"}},
+			},
+		},
+	}
+}
+
+func TestValidateFlagGroups(t *testing.T) {
+	commands := testFlagGroupCommands()
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"export --stdout", ""},
+		{"export --json --stdout", ""},
+		{"export --json --yaml --stdout", "--json and --yaml cannot be used together"},
+		{"export --user me --password secret --output out.json", ""},
+		{"export --user me --stdout", "--user and --password must be used together"},
+		{"export --json", "exactly one of --stdout or --output is required"},
+		{"export --stdout --output out.json", "--stdout and --output cannot be used together"},
+	}
+
+	for _, c := range cases {
+		err := validateCommandInput(parseInput(c.input, commands))
+		if (err == nil && c.expected != "") || (err != nil && err.Error() != c.expected) {
+			t.Errorf("validateCommandInput(%q) == %v, expected %q", c.input, err, c.expected)
+		}
+	}
+
+	atLeastOne := &Command{
+		Command: "notify",
+		Flags: []*Flag{
+			{LongFlag: "--email", Type: StringArgument},
+			{LongFlag: "--slack", Type: StringArgument},
+		},
+		FlagGroups: []FlagGroup{{Kind: AtLeastOneOf, Flags: []string{"--email", "--slack"}}},
+	}
+	err := validateCommandInput(parseInput("notify", []*Command{atLeastOne}))
+	if err == nil || err.Error() != "at least one of --email or --slack is required" {
+		t.Errorf("validateCommandInput(%q) == %v, expected at least one flag required", "notify", err)
+	}
+}
+
+func TestValidateFlagGroupDefinition(t *testing.T) {
+	persistent := &Command{
+		Command: "app",
+		Flags:   []*Flag{{LongFlag: "--quiet", Type: BoolArgument, Persistent: true}},
+		SubCommands: []*Command{
+			{
+				Command:    "run",
+				Flags:      []*Flag{{LongFlag: "--verbose", Type: BoolArgument}},
+				FlagGroups: []FlagGroup{{Kind: MutuallyExclusive, Flags: []string{"--quiet", "--verbose"}}},
+			},
+		},
+	}
+	if err := persistent.Validate(); err != nil {
+		t.Errorf("Validate() == %v, expected groups to include persistent flags", err)
+	}
+
+	unknown := &Command{
+		Command:    "run",
+		Flags:      []*Flag{{LongFlag: "--verbose", Type: BoolArgument}},
+		FlagGroups: []FlagGroup{{Kind: MutuallyExclusive, Flags: []string{"--quiet", "--verbose"}}},
+	}
+	if err := unknown.Validate(); err == nil || err.Error() != "unknown flag in flag group: --quiet" {
+		t.Errorf("Validate() == %v, expected an unknown flag error", err)
+	}
+
+	single := &Command{
+		Command:    "run",
+		Flags:      []*Flag{{LongFlag: "--verbose", Type: BoolArgument}},
+		FlagGroups: []FlagGroup{{Kind: AtLeastOneOf, Flags: []string{"--verbose"}}},
+	}
+	if err := single.Validate(); err == nil {
+		t.Errorf("Validate() == nil, expected an error for a group with one flag")
+	}
+}
+
+func testRequiredFlagCommands() []*Command {
+	return []*Command{
+		{
+			Command: "deploy",
+			Flags: []*Flag{
+				{ShortFlag: "-p", LongFlag: "--project", Type: StringArgument, Required: true, Persistent: true},
+				{LongFlag: "--tls-cert", Type: StringArgument},
+				{LongFlag: "--tls-key", Type: StringArgument, Requires: []string{"--tls-cert"}},
+				{LongFlag: "--verbose", Type: BoolArgument},
+			},
+			SubCommands: []*Command{
+				{Command: "status"},
+			},
+		},
+	}
+}
+
+func TestValidateRequiredFlags(t *testing.T) {
+	commands := testRequiredFlagCommands()
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"deploy -p web", ""},
+		{"deploy", "missing required flag: --project"},
+		{"deploy status", "missing required flag: --project"},
+		{"deploy status --project web", ""},
+		{"deploy -p web --tls-key key.pem", "flag --tls-key requires --tls-cert"},
+		{"deploy -p web --tls-key key.pem --tls-cert cert.pem", ""},
+		{"deploy -p web --tls-cert cert.pem", ""},
+	}
+
+	for _, c := range cases {
+		err := validateCommandInput(parseInput(c.input, commands))
+		if (err == nil && c.expected != "") || (err != nil && err.Error() != c.expected) {
+			t.Errorf("validateCommandInput(%q) == %v, expected %q", c.input, err, c.expected)
+		}
+	}
+
+	unknown := &Command{
+		Command: "serve",
+		Flags:   []*Flag{{LongFlag: "--tls-key", Type: StringArgument, Requires: []string{"--tls-cert"}}},
+	}
+	if err := unknown.Validate(); err == nil || err.Error() != "unknown flag in requires of flag: --tls-key" {
+		t.Errorf("Validate() == %v, expected an unknown flag error", err)
+	}
+}
+
+func testRepeatableFlagCommands() []*Command {
+	return []*Command{
+		{
+			Command: "build",
+			Flags: []*Flag{
+				{ShortFlag: "-v", LongFlag: "--verbose", Type: BoolArgument, Count: true},
+				{ShortFlag: "-t", LongFlag: "--tag", Type: StringArgument, Repeatable: true},
+				{LongFlag: "--port", Type: IntArgument, Repeatable: true},
+				{LongFlag: "--output", Type: StringArgument},
+			},
+		},
+	}
+}
+
+func TestValidateRepeatableFlags(t *testing.T) {
+	commands := testRepeatableFlagCommands()
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"build -vvv -t a --tag b", ""},
+		{"build -v -v --port 80 --port 443", ""},
+		{"build --port 80 --port http", "invalid integer value for argument: --port"},
+		{"build --verbose=true", "count flag '--verbose' doesn't take a value"},
+	}
+
+	for _, c := range cases {
+		err := validateCommandInput(parseInput(c.input, commands))
+		if (err == nil && c.expected != "") || (err != nil && err.Error() != c.expected) {
+			t.Errorf("validateCommandInput(%q) == %v, expected %q", c.input, err, c.expected)
+		}
+	}
+
+	if err := (&Flag{LongFlag: "--level", Type: IntArgument, Count: true}).Validate(); err == nil {
+		t.Errorf("Validate() == nil, expected an error for a count flag that isn't a bool")
+	}
+}
+
+func testVariadicCommands() []*Command {
+	return []*Command{
+		{
+			Command: "rm",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "file", Type: FileArgument, Required: true, Variadic: true},
+			},
+		},
+		{
+			Command: "join",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "separator", Type: StringArgument, Required: true},
+				{Name: "part", Type: IntArgument, Variadic: true, MinCount: 2, MaxCount: 3},
+			},
+		},
+	}
+}
+
+func TestValidateVariadicArguments(t *testing.T) {
+	commands := testVariadicCommands()
+
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{"join , 1 2", ""},
+		{"join , 1 2 3", ""},
+		{"join ,", "missing positional argument: part"},
+		{"join , 1", "expected at least 2 values for argument: part"},
+		{"join , 1 2 3 4", "too many values for argument: part (at most 3)"},
+		{"join , 1 two", "invalid integer value for argument: part"},
+		{"rm", "missing positional argument: file"},
+	}
+
+	for _, c := range cases {
+		err := validateCommandInput(parseInput(c.input, commands))
+		if (err == nil && c.expected != "") || (err != nil && err.Error() != c.expected) {
+			t.Errorf("validateCommandInput(%q) == %v, expected %q", c.input, err, c.expected)
+		}
+	}
+
+	definitions := []*Command{
+		{
+			Command: "cat",
+			PositionalArguments: []*PositionalArgument{
+				{Name: "files", Type: StringArgument, Variadic: true},
+				{Name: "output", Type: StringArgument},
+			},
+		},
+		{
+			Command:             "cat",
+			PositionalArguments: []*PositionalArgument{{Name: "file", Type: StringArgument, MaxCount: 2}},
+		},
+		{
+			Command:             "cat",
+			PositionalArguments: []*PositionalArgument{{Name: "files", Type: StringArgument, Variadic: true, MinCount: 3, MaxCount: 2}},
+		},
+	}
+	for _, cmd := range definitions {
+		if err := cmd.Validate(); err == nil {
+			t.Errorf("Validate() == nil, expected an error for %+v", cmd.PositionalArguments)
+		}
 	}
 }